@@ -3,8 +3,8 @@ module github.com/bartosz-skejcik/tmux-setup
 go 1.23.4
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	golang.org/x/term v0.27.0
-	gopkg.in/yaml.v2 v2.4.0
 	gopkg.in/yaml.v3 v3.0.1
 )
 
@@ -0,0 +1,258 @@
+package cli
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"github.com/bartosz-skejcik/tmux-setup/internal/tmux"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK    = 0
+	ExitError = 1
+	ExitUsage = 2
+)
+
+// Command is a node in the command tree
+type Command struct {
+	Name  string
+	Args  string // argument synopsis shown in usage, e.g. "[session]"
+	Short string
+	// Hidden commands are dispatched but left out of usage text
+	Hidden   bool
+	SetFlags func(flags *flag.FlagSet)
+	Run      func(args []string) error
+	// Complete returns shell completion candidates for the next positional
+	// argument, given the ones already typed
+	Complete    func(args []string) []string
+	Subcommands []*Command
+}
+
+// usageError marks an error caused by invalid invocation
+type usageError struct {
+	msg string
+}
+
+func (e usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// global flags accepted before the command name and by every command
+var globals struct {
+	configPath string
+	// settings are the user's preferences from config.yml
+	settings config.Settings
+}
+
+// globalFlags holds the global flags parsed by one FlagSet. They're copied
+// to globals only when given, so the flags of a command don't reset a
+// --config given before its name.
+type globalFlags struct {
+	configPath string
+}
+
+func addGlobalFlags(flags *flag.FlagSet) *globalFlags {
+	values := &globalFlags{}
+	flags.StringVar(&values.configPath, "config", "", "Path to the project config file (default: nearest tmux.conf.yml)")
+	return values
+}
+
+// apply copies the global flags given to flags into globals
+func (g *globalFlags) apply(flags *flag.FlagSet) {
+	flags.Visit(func(f *flag.Flag) {
+		if f.Name == "config" {
+			globals.configPath = g.configPath
+		}
+	})
+}
+
+// Run executes the command line (without the program name) and returns
+// the process exit code
+func Run(args []string) int {
+	root := rootCommand()
+	globals.configPath = ""
+
+	// Global flags may precede the command name
+	rootFlags := flag.NewFlagSet(root.Name, flag.ContinueOnError)
+	rootFlags.SetOutput(io.Discard)
+	rootGlobals := addGlobalFlags(rootFlags)
+	for len(args) > 0 && isGlobalFlag(args[0]) {
+		n := 1
+		if !strings.Contains(args[0], "=") && len(args) > 1 {
+			n = 2
+		}
+		if err := rootFlags.Parse(args[:n]); err != nil {
+			return fail(root, root.Name, usagef("%v", err))
+		}
+		args = args[n:]
+	}
+	rootGlobals.apply(rootFlags)
+
+	if len(args) > 0 && (args[0] == "-h" || args[0] == "-help" || args[0] == "--help" || args[0] == "help") {
+		if len(args) > 1 {
+			if cmd, _ := find(root, args[1:]); cmd != root {
+				printUsage(os.Stdout, cmd, commandPath(root, args[1:]))
+				return ExitOK
+			}
+		}
+		printUsage(os.Stdout, root, root.Name)
+		return ExitOK
+	}
+
+	// Without a command name (or with only flags) the session is started
+	if len(args) == 0 || strings.HasPrefix(args[0], "-") {
+		args = append([]string{"start"}, args...)
+	}
+
+	cmd, rest := find(root, args)
+	path := commandPath(root, args[:len(args)-len(rest)])
+	if cmd == root {
+		return fail(root, root.Name, usagef("unknown command %q", args[0]))
+	}
+	if cmd.Run == nil {
+		if len(rest) == 0 {
+			return fail(cmd, path, usagef("%s requires a subcommand", path))
+		}
+		return fail(cmd, path, usagef("unknown %s command %q", path, rest[0]))
+	}
+
+	flags := flag.NewFlagSet(path, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	commandGlobals := addGlobalFlags(flags)
+	if cmd.SetFlags != nil {
+		cmd.SetFlags(flags)
+	}
+	if err := flags.Parse(rest); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			printUsageWithFlags(os.Stdout, cmd, path, flags)
+			return ExitOK
+		}
+		return failWithFlags(cmd, path, flags, usagef("%v", err))
+	}
+	commandGlobals.apply(flags)
+
+	settings, err := config.LoadSettings()
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: failed to load settings: %v\n", err)
+		return ExitError
+	}
+	globals.settings = settings
+	tmux.Socket = settings.Socket
+
+	if err := cmd.Run(flags.Args()); err != nil {
+		var usage usageError
+		if errors.As(err, &usage) {
+			return failWithFlags(cmd, path, flags, err)
+		}
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return ExitError
+	}
+	return ExitOK
+}
+
+func isGlobalFlag(arg string) bool {
+	name := strings.TrimLeft(arg, "-")
+	name, _, _ = strings.Cut(name, "=")
+	return strings.HasPrefix(arg, "-") && name == "config"
+}
+
+// find walks the command tree along args and returns the deepest matching
+// command together with the remaining arguments
+func find(cmd *Command, args []string) (*Command, []string) {
+	for len(args) > 0 {
+		next := lookup(cmd, args[0])
+		if next == nil {
+			break
+		}
+		cmd, args = next, args[1:]
+	}
+	return cmd, args
+}
+
+func lookup(cmd *Command, name string) *Command {
+	for _, sub := range cmd.Subcommands {
+		if sub.Name == name {
+			return sub
+		}
+	}
+	return nil
+}
+
+func commandPath(root *Command, args []string) string {
+	names := []string{root.Name}
+	cmd := root
+	for _, arg := range args {
+		next := lookup(cmd, arg)
+		if next == nil {
+			break
+		}
+		names = append(names, next.Name)
+		cmd = next
+	}
+	return strings.Join(names, " ")
+}
+
+func fail(cmd *Command, path string, err error) int {
+	fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+	printUsage(os.Stderr, cmd, path)
+	return ExitUsage
+}
+
+func failWithFlags(cmd *Command, path string, flags *flag.FlagSet, err error) int {
+	fmt.Fprintf(os.Stderr, "Error: %v\n\n", err)
+	printUsageWithFlags(os.Stderr, cmd, path, flags)
+	return ExitUsage
+}
+
+func printUsage(w io.Writer, cmd *Command, path string) {
+	flags := flag.NewFlagSet(path, flag.ContinueOnError)
+	addGlobalFlags(flags)
+	if cmd.SetFlags != nil {
+		cmd.SetFlags(flags)
+	}
+	printUsageWithFlags(w, cmd, path, flags)
+}
+
+func printUsageWithFlags(w io.Writer, cmd *Command, path string, flags *flag.FlagSet) {
+	if cmd.Short != "" {
+		fmt.Fprintf(w, "%s\n\n", cmd.Short)
+	}
+
+	fmt.Fprintln(w, "Usage:")
+	if cmd.Run != nil {
+		fmt.Fprintf(w, "  %s\n", strings.TrimSpace(path+" [flags] "+cmd.Args))
+	}
+	if len(cmd.Subcommands) > 0 {
+		fmt.Fprintf(w, "  %s <command> [flags]\n", path)
+		fmt.Fprintln(w, "\nCommands:")
+		for _, sub := range cmd.Subcommands {
+			if sub.Hidden {
+				continue
+			}
+			name := sub.Name
+			if sub.Args != "" {
+				name += " " + sub.Args
+			}
+			fmt.Fprintf(w, "  %-26s %s\n", name, sub.Short)
+		}
+	}
+
+	fmt.Fprintln(w, "\nFlags:")
+	flags.SetOutput(w)
+	flags.PrintDefaults()
+	flags.SetOutput(io.Discard)
+
+	if len(cmd.Subcommands) > 0 {
+		fmt.Fprintf(w, "\nRun '%s <command> --help' for details on a command.\n", path)
+	}
+}
@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// runCLI runs a command line and returns its exit code and output
+func runCLI(t *testing.T, args ...string) (code int, stdout, stderr string) {
+	t.Helper()
+	dir := t.TempDir()
+	outFile, err := os.Create(filepath.Join(dir, "stdout"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	errFile, err := os.Create(filepath.Join(dir, "stderr"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	savedOut, savedErr := os.Stdout, os.Stderr
+	os.Stdout, os.Stderr = outFile, errFile
+	code = Run(args)
+	os.Stdout, os.Stderr = savedOut, savedErr
+	outFile.Close()
+	errFile.Close()
+
+	out, _ := os.ReadFile(outFile.Name())
+	errOut, _ := os.ReadFile(errFile.Name())
+	return code, string(out), string(errOut)
+}
+
+// chdir changes the working directory for the rest of the test
+func chdir(t *testing.T, dir string) {
+	t.Helper()
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+}
+
+// writeProject writes a project config naming the session and its window
+func writeProject(t *testing.T, dir, session string) string {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(dir, "tmux.conf.yml")
+	data := "session_name: " + session + "\nwindows:\n  - name: editor\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMUX_SETUP_HOME", filepath.Join(dir, "home"))
+	alpha := writeProject(t, filepath.Join(dir, "alpha"), "alpha")
+	beta := writeProject(t, filepath.Join(dir, "beta"), "beta")
+	missing := filepath.Join(dir, "missing.yml")
+	// The nearest project is alpha
+	chdir(t, filepath.Dir(alpha))
+
+	tests := []struct {
+		name       string
+		args       []string
+		wantCode   int
+		wantStdout string
+		wantStderr string
+	}{
+		{name: "help", args: []string{"help"}, wantCode: ExitOK, wantStdout: "Usage:"},
+		{name: "command help", args: []string{"plan", "--help"}, wantCode: ExitOK, wantStdout: "tmux-setup plan [flags]"},
+		{name: "unknown command", args: []string{"bogus"}, wantCode: ExitUsage, wantStderr: `Error: unknown command "bogus"`},
+		{name: "missing subcommand", args: []string{"config"}, wantCode: ExitUsage, wantStderr: "tmux-setup config requires a subcommand"},
+		{name: "unknown subcommand", args: []string{"config", "nope"}, wantCode: ExitUsage, wantStderr: `unknown tmux-setup config command "nope"`},
+		{name: "unknown flag", args: []string{"plan", "--bogus"}, wantCode: ExitUsage, wantStderr: "flag provided but not defined: -bogus"},
+		{name: "unexpected argument", args: []string{"plan", "extra"}, wantCode: ExitUsage, wantStderr: "unexpected arguments: [extra]"},
+		{name: "template without a value", args: []string{"--template"}, wantCode: ExitUsage, wantStderr: "flag needs an argument: -template"},
+		{name: "config without a value", args: []string{"--config"}, wantCode: ExitUsage, wantStderr: "flag needs an argument: -config"},
+		{name: "nearest project", args: []string{"plan"}, wantCode: ExitOK, wantStdout: "Session alpha"},
+		{name: "config before the command", args: []string{"--config", beta, "plan"}, wantCode: ExitOK, wantStdout: "Session beta"},
+		{name: "config= before the command", args: []string{"--config=" + beta, "plan"}, wantCode: ExitOK, wantStdout: "Session beta"},
+		{name: "config after the command", args: []string{"plan", "--config", beta}, wantCode: ExitOK, wantStdout: "Session beta"},
+		{name: "config of the command wins", args: []string{"--config", beta, "plan", "--config", alpha}, wantCode: ExitOK, wantStdout: "Session alpha"},
+		{name: "missing config before the command", args: []string{"--config", missing, "plan"}, wantCode: ExitError, wantStderr: missing},
+		{name: "missing config after the command", args: []string{"plan", "--config", missing}, wantCode: ExitError, wantStderr: missing},
+		{name: "validate", args: []string{"validate", "--config", beta}, wantCode: ExitOK, wantStdout: beta + ": OK"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, test.args...)
+			if code != test.wantCode {
+				t.Errorf("exit code = %d, want %d\nstderr: %s", code, test.wantCode, stderr)
+			}
+			if !strings.Contains(stdout, test.wantStdout) {
+				t.Errorf("stdout = %q, want it to contain %q", stdout, test.wantStdout)
+			}
+			if !strings.Contains(stderr, test.wantStderr) {
+				t.Errorf("stderr = %q, want it to contain %q", stderr, test.wantStderr)
+			}
+			if test.wantCode == ExitOK && stderr != "" {
+				t.Errorf("unexpected stderr: %s", stderr)
+			}
+		})
+	}
+}
+
+func TestRunConfigOutsideProject(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMUX_SETUP_HOME", filepath.Join(dir, "home"))
+	project := writeProject(t, filepath.Join(dir, "project"), "project")
+	empty := filepath.Join(dir, "empty")
+	if err := os.Mkdir(empty, 0755); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, empty)
+
+	for _, args := range [][]string{
+		{"--config", project, "plan"},
+		{"plan", "--config", project},
+	} {
+		if code, stdout, stderr := runCLI(t, args...); code != ExitOK || !strings.Contains(stdout, "Session project") {
+			t.Errorf("%v: exit code %d, stdout %q, stderr %q", args, code, stdout, stderr)
+		}
+	}
+
+	code, _, stderr := runCLI(t, "plan")
+	if code != ExitError || !strings.Contains(stderr, "no tmux.conf.yml") {
+		t.Errorf("plan without a project: exit code %d, stderr %q", code, stderr)
+	}
+}
+
+func TestConfigEdit(t *testing.T) {
+	dir := t.TempDir()
+	home := filepath.Join(dir, "home")
+	t.Setenv("TMUX_SETUP_HOME", home)
+	t.Setenv("VISUAL", "")
+	t.Setenv("EDITOR", "echo from-editor")
+	project := writeProject(t, filepath.Join(dir, "project"), "project")
+	chdir(t, filepath.Dir(project))
+
+	code, stdout, stderr := runCLI(t, "config", "edit")
+	if code != ExitOK || stdout != "from-editor "+project+"\n" {
+		t.Errorf("config edit with $EDITOR: exit code %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+
+	// The editor setting wins over $EDITOR
+	if err := os.MkdirAll(home, 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(home, "config.yml"), []byte("editor: echo from-settings\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	code, stdout, stderr = runCLI(t, "config", "edit")
+	if code != ExitOK || stdout != "from-settings "+project+"\n" {
+		t.Errorf("config edit with the setting: exit code %d, stdout %q, stderr %q", code, stdout, stderr)
+	}
+}
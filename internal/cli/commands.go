@@ -0,0 +1,360 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"github.com/bartosz-skejcik/tmux-setup/internal/hooks"
+	"github.com/bartosz-skejcik/tmux-setup/internal/tmux"
+)
+
+func rootCommand() *Command {
+	return &Command{
+		Name:  "tmux-setup",
+		Short: "Create tmux sessions from a tmux.conf.yml project file.",
+		Subcommands: []*Command{
+			startCommand(),
+			stopCommand(),
+			attachCommand(),
+			lsCommand(),
+			planCommand(),
+			validateCommand(),
+			schemaCommand(),
+			configCommand(),
+			convertCommand(),
+			importCommand(),
+			exportCommand(),
+			initCommand(),
+			wizardCommand(),
+			templateCommand(),
+			logsCommand(),
+			allowCommand(),
+			denyCommand(),
+			trustCommand(),
+			completionCommand(),
+			completeCommand(),
+		},
+	}
+}
+
+// sourceFlags select the configuration a command works on
+type sourceFlags struct {
+	template string
+	set      stringList
+	profiles stringList
+	// runCommands lets when conditions run their commands
+	runCommands bool
+}
+
+func (s *sourceFlags) register(flags *flag.FlagSet) {
+	flags.StringVar(&s.template, "template", "", "Use a template from ~/.config/tmux-setup/templates/ instead of a project file")
+	flags.Var(&s.set, "set", "Set a template parameter as key=value (repeatable)")
+	flags.Var(&s.profiles, "profile", "Apply profiles from the config, comma-separated (repeatable)")
+}
+
+// options returns the load options given by the flags
+func (s *sourceFlags) options() (config.Options, error) {
+	values, err := config.ParseSet(s.set)
+	if err != nil {
+		return config.Options{}, usagef("invalid --set: %v", err)
+	}
+	return config.Options{Set: values, Profiles: s.profileNames(), RunCommands: s.runCommands}, nil
+}
+
+// profileNames returns the profiles given with --profile, in order
+func (s *sourceFlags) profileNames() []string {
+	var names []string
+	for _, value := range s.profiles {
+		for _, name := range strings.Split(value, ",") {
+			if name = strings.TrimSpace(name); name != "" {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+// stringList is a flag that can be given several times
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ", ")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// templateName returns the template used instead of a project file: the
+// --template flag, or the default template when there is no project file
+func (s *sourceFlags) templateName() string {
+	if s.template != "" {
+		return s.template
+	}
+	if globals.configPath == "" && config.FindConfigFile() == "" {
+		return globals.settings.DefaultTemplate
+	}
+	return ""
+}
+
+// load resolves the configuration selected by the global and source flags.
+// The returned path is empty when the configuration comes from a template.
+func (s *sourceFlags) load() (config.Config, string, error) {
+	opts, err := s.options()
+	if err != nil {
+		return config.Config{}, "", err
+	}
+
+	if name := s.templateName(); name != "" {
+		cfg, err := config.LoadTemplateWithOptions(name, opts)
+		if err != nil {
+			return cfg, "", fmt.Errorf("failed to load template: %v", err)
+		}
+		return cfg, "", nil
+	}
+
+	path, err := configPath()
+	if err != nil {
+		return config.Config{}, "", err
+	}
+	cfg, err := config.LoadWithOptions(path, opts)
+	if err != nil {
+		return cfg, path, fmt.Errorf("failed to load configuration: %v", err)
+	}
+	return cfg, path, nil
+}
+
+// path returns the file the selected configuration is read from
+func (s *sourceFlags) path() (string, error) {
+	if name := s.templateName(); name != "" {
+		return config.TemplatePath(name)
+	}
+	return configPath()
+}
+
+// configPath returns the --config path, or the nearest project config file
+func configPath() (string, error) {
+	if globals.configPath != "" {
+		return globals.configPath, nil
+	}
+	path := config.FindConfigFile()
+	if path == "" {
+		return "", fmt.Errorf("no tmux.conf.yml (or .yaml, .toml, .json) found in current or parent directories")
+	}
+	return path, nil
+}
+
+// sessionName returns the session name of cfg, or one following the naming
+// scheme of the settings
+func sessionName(cfg config.Config) string {
+	if cfg.SessionName != "" {
+		return cfg.SessionName
+	}
+	return globals.settings.SessionNameFor(projectDir())
+}
+
+// projectDir returns the directory of the project config, or the working
+// directory without one
+func projectDir() string {
+	if path, err := configPath(); err == nil {
+		return filepath.Dir(path)
+	}
+	dir, _ := os.Getwd()
+	return dir
+}
+
+// sessionArg returns the session named in args, or the configured one
+func sessionArg(args []string) (string, error) {
+	switch len(args) {
+	case 0:
+		var source sourceFlags
+		cfg, _, err := source.load()
+		if err != nil {
+			return "", err
+		}
+		return sessionName(cfg), nil
+	case 1:
+		return args[0], nil
+	}
+	return "", usagef("expected at most one session name")
+}
+
+func startCommand() *Command {
+	var source sourceFlags
+	var detach, attach bool
+
+	return &Command{
+		Name:  "start",
+		Short: "Create the session from the config and attach to it (default command)",
+		SetFlags: func(flags *flag.FlagSet) {
+			source.register(flags)
+			flags.BoolVar(&detach, "detach", false, "Create the session without attaching to it")
+			flags.BoolVar(&detach, "d", false, "Shorthand for --detach")
+			flags.BoolVar(&attach, "attach", false, "Attach to the session even if the settings say not to")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+
+			cfg, path, err := source.load()
+			if err != nil {
+				return err
+			}
+
+			// Project configs must be approved, with every file they read,
+			// before any of their commands run
+			if path != "" {
+				opts, err := source.options()
+				if err != nil {
+					return err
+				}
+				files, err := config.ProjectFiles(path, opts)
+				if err != nil {
+					return err
+				}
+				if err := checkTrust(path, files, cfg); err != nil {
+					return err
+				}
+			}
+
+			// when conditions can run commands too, so they are only
+			// checked once the config is trusted
+			source.runCommands = true
+			if cfg, _, err = source.load(); err != nil {
+				return err
+			}
+
+			if len(cfg.Dependencies) > 0 {
+				if err := tmux.CheckDependencies(cfg.Dependencies); err != nil {
+					return err
+				}
+			}
+
+			name := sessionName(cfg)
+			profile := strings.Join(source.profileNames(), ",")
+			if tmux.HasSession(name) {
+				fmt.Fprintf(os.Stderr, "Session %s is already running\n", name)
+			} else if err := createSession(name, cfg); err != nil {
+				return err
+			} else if profile != "" {
+				if err := tmux.SetSessionOption(name, tmux.ProfileOption, profile); err != nil {
+					fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+				}
+			}
+
+			if detach || !(attach || globals.settings.Attach) {
+				return nil
+			}
+			if err := tmux.AttachSession(name, cfg.Focus()); err != nil {
+				return fmt.Errorf("failed to attach to tmux session: %v", err)
+			}
+			return nil
+		},
+	}
+}
+
+func createSession(name string, cfg config.Config) error {
+	if _, err := hooks.StartRun(name); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: failed to open hook log: %v\n", err)
+	}
+	defer hooks.EndRun()
+
+	if err := hooks.RunPreSessionHooks(cfg); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: pre-session hooks failed: %v\n", err)
+	}
+
+	// The post-session hook is bound to tmux's session-closed event here
+	if err := tmux.CreateSession(name, cfg); err != nil {
+		return fmt.Errorf("failed to create tmux session: %v", err)
+	}
+	return nil
+}
+
+func stopCommand() *Command {
+	return &Command{
+		Name:  "stop",
+		Args:  "[session]",
+		Short: "Kill the configured session, or the named one",
+		Complete: func(args []string) []string {
+			if len(args) == 0 {
+				return completeSessions()
+			}
+			return nil
+		},
+		Run: func(args []string) error {
+			name, err := sessionArg(args)
+			if err != nil {
+				return err
+			}
+			return tmux.KillSession(name)
+		},
+	}
+}
+
+func attachCommand() *Command {
+	var window string
+
+	return &Command{
+		Name:  "attach",
+		Args:  "[session]",
+		Short: "Attach to the configured session, or the named one",
+		SetFlags: func(flags *flag.FlagSet) {
+			flags.StringVar(&window, "window", "", "Select the window with this name before attaching")
+		},
+		Complete: func(args []string) []string {
+			if len(args) == 0 {
+				return completeSessions()
+			}
+			return nil
+		},
+		Run: func(args []string) error {
+			name, err := sessionArg(args)
+			if err != nil {
+				return err
+			}
+			if !tmux.HasSession(name) {
+				return fmt.Errorf("session %s is not running", name)
+			}
+			if window != "" {
+				if err := tmux.SelectWindow(name, window); err != nil {
+					return err
+				}
+			}
+			return tmux.Attach(name)
+		},
+	}
+}
+
+func lsCommand() *Command {
+	return &Command{
+		Name:  "ls",
+		Short: "List running tmux sessions",
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+
+			sessions, err := tmux.ListSessions()
+			if err != nil {
+				return err
+			}
+			for _, session := range sessions {
+				details := ""
+				if session.Profile != "" {
+					details += " [profile " + session.Profile + "]"
+				}
+				if session.Attached {
+					details += " (attached)"
+				}
+				fmt.Printf("%s: %d windows%s\n", session.Name, session.Windows, details)
+			}
+			return nil
+		},
+	}
+}
@@ -0,0 +1,252 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"github.com/bartosz-skejcik/tmux-setup/internal/tmux"
+)
+
+// The generated scripts delegate to the hidden __complete command, so
+// completion always matches the command tree of the installed binary.
+const bashCompletion = `# bash completion for tmux-setup
+_tmux_setup() {
+    local IFS=$'\n'
+    COMPREPLY=($(tmux-setup __complete -- "${COMP_WORDS[@]:1:COMP_CWORD}" 2>/dev/null))
+}
+complete -o default -F _tmux_setup tmux-setup
+`
+
+const zshCompletion = `#compdef tmux-setup
+# zsh completion for tmux-setup
+_tmux_setup() {
+    local -a candidates
+    candidates=("${(@f)$(tmux-setup __complete -- "${(@)words[2,CURRENT]}" 2>/dev/null)}")
+    if [[ -n "${candidates[1]}" ]]; then
+        compadd -a candidates
+    else
+        _files
+    fi
+}
+compdef _tmux_setup tmux-setup
+`
+
+const fishCompletion = `# fish completion for tmux-setup
+function __tmux_setup_complete
+    set -l tokens (commandline -opc) (commandline -ct)
+    tmux-setup __complete -- $tokens[2..-1] 2>/dev/null
+end
+complete -c tmux-setup -f -a '(__tmux_setup_complete)'
+`
+
+func completionCommand() *Command {
+	return &Command{
+		Name:  "completion",
+		Args:  "bash|zsh|fish",
+		Short: "Print a shell completion script",
+		Complete: func(args []string) []string {
+			return []string{"bash", "zsh", "fish"}
+		},
+		Run: func(args []string) error {
+			if len(args) != 1 {
+				return usagef("expected a shell name")
+			}
+
+			switch args[0] {
+			case "bash":
+				fmt.Print(bashCompletion)
+			case "zsh":
+				fmt.Print(zshCompletion)
+			case "fish":
+				fmt.Print(fishCompletion)
+			default:
+				return usagef("unsupported shell %q", args[0])
+			}
+			return nil
+		},
+	}
+}
+
+func completeCommand() *Command {
+	return &Command{
+		Name:   "__complete",
+		Hidden: true,
+		Run: func(args []string) error {
+			complete(os.Stdout, rootCommand(), args)
+			return nil
+		},
+	}
+}
+
+// complete prints the candidates for the last word in words, given the
+// words typed before it
+func complete(w io.Writer, root *Command, words []string) {
+	if len(words) == 0 {
+		words = []string{""}
+	}
+	current := words[len(words)-1]
+
+	cmd := root
+	// Flags given without a command belong to the implied start command
+	flags := commandFlags(lookup(root, "start"))
+	var positional []string
+	var pendingFlag *flag.Flag
+
+	for _, word := range words[:len(words)-1] {
+		if pendingFlag != nil {
+			setCompletionFlag(pendingFlag.Name, word)
+			pendingFlag = nil
+			continue
+		}
+		if strings.HasPrefix(word, "-") {
+			name := strings.TrimLeft(word, "-")
+			if name, value, ok := strings.Cut(name, "="); ok {
+				setCompletionFlag(name, value)
+			} else if f := flags.Lookup(name); f != nil && !isBoolFlag(f) {
+				pendingFlag = f
+			}
+			continue
+		}
+		if sub := lookup(cmd, word); sub != nil && len(positional) == 0 {
+			cmd = sub
+			flags = commandFlags(cmd)
+			continue
+		}
+		positional = append(positional, word)
+	}
+
+	var candidates []string
+	switch {
+	case pendingFlag != nil:
+		candidates = flagValues(pendingFlag.Name, current)
+	case strings.HasPrefix(current, "-"):
+		flags.VisitAll(func(f *flag.Flag) {
+			candidates = append(candidates, "--"+f.Name)
+		})
+	default:
+		if len(positional) == 0 {
+			for _, sub := range cmd.Subcommands {
+				if !sub.Hidden {
+					candidates = append(candidates, sub.Name)
+				}
+			}
+		}
+		if cmd.Complete != nil {
+			candidates = append(candidates, cmd.Complete(positional)...)
+		}
+	}
+
+	for _, candidate := range candidates {
+		if strings.HasPrefix(candidate, current) {
+			fmt.Fprintln(w, candidate)
+		}
+	}
+}
+
+// setCompletionFlag records the value of a global flag typed before the
+// word being completed, so a --config on the command line picks the
+// project whose windows and profiles are completed
+func setCompletionFlag(name, value string) {
+	if name == "config" {
+		globals.configPath = value
+	}
+}
+
+func commandFlags(cmd *Command) *flag.FlagSet {
+	flags := flag.NewFlagSet(cmd.Name, flag.ContinueOnError)
+	addGlobalFlags(flags)
+	if cmd.SetFlags != nil {
+		cmd.SetFlags(flags)
+	}
+	return flags
+}
+
+func isBoolFlag(f *flag.Flag) bool {
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
+
+// flagValues returns the candidates for the value of a flag
+func flagValues(name, current string) []string {
+	switch name {
+	case "config":
+		return completeFiles(current)
+	case "template", "create-template":
+		return completeTemplates()
+	case "window":
+		return completeWindows()
+	case "profile":
+		return completeProfiles()
+	case "to":
+		return config.Formats
+	case "format":
+		return []string{"tmuxp"}
+	}
+	return nil
+}
+
+func completeFiles(current string) []string {
+	matches, _ := filepath.Glob(current + "*")
+	for i, match := range matches {
+		if info, err := os.Stat(match); err == nil && info.IsDir() {
+			matches[i] = match + string(filepath.Separator)
+		}
+	}
+	return matches
+}
+
+// completeTemplates returns the names of the templates in the config dir
+func completeTemplates() []string {
+	names, _ := config.ListTemplates()
+	return names
+}
+
+// completeSessions returns the names of the running tmux sessions
+func completeSessions() []string {
+	sessions, _ := tmux.ListSessions()
+	names := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		names = append(names, session.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// completeWindows returns the window names of the project config
+func completeWindows() []string {
+	path, err := configPath()
+	if err != nil {
+		return nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil
+	}
+
+	var names []string
+	for _, window := range cfg.Windows {
+		if window.Name != "" {
+			names = append(names, window.Name)
+		}
+	}
+	return names
+}
+
+// completeProfiles returns the profiles of the project config file
+func completeProfiles() []string {
+	path, err := configPath()
+	if err != nil {
+		return nil
+	}
+	cfg, err := config.Load(path)
+	if err != nil {
+		return nil
+	}
+	return config.ProfileNames(cfg)
+}
@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestComplete(t *testing.T) {
+	dir := t.TempDir()
+	t.Setenv("TMUX_SETUP_HOME", filepath.Join(dir, "home"))
+	alpha := writeProject(t, filepath.Join(dir, "alpha"), "alpha")
+	beta := filepath.Join(dir, "beta", "tmux.conf.yml")
+	if err := os.MkdirAll(filepath.Dir(beta), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data := "windows:\n  - name: server\n  - name: logs\nprofiles:\n  debug:\n    env:\n      DEBUG: \"1\"\n"
+	if err := os.WriteFile(beta, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	chdir(t, filepath.Dir(alpha))
+
+	tests := []struct {
+		name  string
+		words []string
+		want  []string
+	}{
+		{name: "commands", words: []string{"att"}, want: []string{"attach"}},
+		{name: "flags", words: []string{"attach", "--win"}, want: []string{"--window"}},
+		{name: "windows of the nearest project", words: []string{"attach", "--window", ""}, want: []string{"editor"}},
+		{name: "windows with config after the command", words: []string{"attach", "--config", beta, "--window", ""}, want: []string{"server", "logs"}},
+		{name: "windows with config before the command", words: []string{"--config", beta, "attach", "--window", ""}, want: []string{"server", "logs"}},
+		{name: "windows with config=", words: []string{"attach", "--config=" + beta, "--window", "l"}, want: []string{"logs"}},
+		{name: "profiles with config", words: []string{"--config", beta, "--profile", ""}, want: []string{"debug"}},
+		{name: "no profiles in the nearest project", words: []string{"--profile", ""}, want: []string{}},
+		{name: "missing config", words: []string{"attach", "--config", filepath.Join(dir, "missing.yml"), "--window", ""}, want: []string{}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			code, stdout, stderr := runCLI(t, append([]string{"__complete", "--"}, test.words...)...)
+			if code != ExitOK {
+				t.Fatalf("exit code = %d, stderr: %s", code, stderr)
+			}
+			got := strings.Fields(stdout)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("candidates = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,227 @@
+package cli
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func configCommand() *Command {
+	return &Command{
+		Name:  "config",
+		Short: "Inspect the project configuration",
+		Subcommands: []*Command{
+			configShowCommand(),
+			configEditCommand(),
+		},
+	}
+}
+
+func configShowCommand() *Command {
+	var source sourceFlags
+	var resolved, asJSON bool
+
+	return &Command{
+		Name:  "show",
+		Short: "Print the config file, or the final config with --resolved",
+		SetFlags: func(flags *flag.FlagSet) {
+			source.register(flags)
+			flags.BoolVar(&resolved, "resolved", false, "Print the config after templates, inheritance and interpolation")
+			flags.BoolVar(&asJSON, "json", false, "Print the resolved config as JSON")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+			if asJSON && !resolved {
+				return usagef("--json requires --resolved")
+			}
+
+			if !resolved {
+				path, err := source.path()
+				if err != nil {
+					return err
+				}
+				data, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				os.Stdout.Write(data)
+				return nil
+			}
+
+			r, err := source.resolve()
+			if err != nil {
+				return err
+			}
+			if asJSON {
+				return printResolvedJSON(os.Stdout, r)
+			}
+			printResolved(os.Stdout, r)
+			return nil
+		},
+	}
+}
+
+func configEditCommand() *Command {
+	var source sourceFlags
+
+	return &Command{
+		Name:     "edit",
+		Short:    "Open the config file in the editor from the settings, $VISUAL or $EDITOR",
+		SetFlags: source.register,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+
+			path, err := source.path()
+			if err != nil {
+				return err
+			}
+
+			// The editor may come with arguments, e.g. "code --wait"
+			editor := exec.Command("sh", "-c", globals.settings.EditorCommand()+` "$@"`, "sh", path)
+			editor.Stdin, editor.Stdout, editor.Stderr = os.Stdin, os.Stdout, os.Stderr
+			return editor.Run()
+		},
+	}
+}
+
+func convertCommand() *Command {
+	var format string
+	var write bool
+
+	return &Command{
+		Name:  "convert",
+		Args:  "[file]",
+		Short: "Convert a config file between YAML, TOML and JSON",
+		SetFlags: func(flags *flag.FlagSet) {
+			flags.StringVar(&format, "to", "", "Format to convert to: yaml, toml or json")
+			flags.BoolVar(&write, "write", false, "Write the result next to the file, e.g. tmux.conf.toml, instead of printing it")
+		},
+		Run: func(args []string) error {
+			if len(args) > 1 {
+				return usagef("unexpected arguments: %v", args[1:])
+			}
+			if !slices.Contains(config.Formats, format) {
+				return usagef("--to must be one of %s", strings.Join(config.Formats, ", "))
+			}
+
+			var path string
+			if len(args) == 1 {
+				path = args[0]
+			} else {
+				var err error
+				if path, err = configPath(); err != nil {
+					return err
+				}
+			}
+
+			data, err := config.Convert(path, format)
+			if err != nil {
+				return err
+			}
+			if !write {
+				os.Stdout.Write(data)
+				return nil
+			}
+
+			target := strings.TrimSuffix(path, filepath.Ext(path)) + config.FormatExt(format)
+			if config.Format(target) == config.Format(path) {
+				return fmt.Errorf("%s is already %s", path, format)
+			}
+			file, err := os.OpenFile(target, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0644)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			if _, err := file.Write(data); err != nil {
+				return err
+			}
+			fmt.Printf("Wrote %s\n", target)
+			return nil
+		},
+	}
+}
+
+// resolve is load with the origin of every value
+func (s *sourceFlags) resolve() (config.Resolved, error) {
+	opts, err := s.options()
+	if err != nil {
+		return config.Resolved{}, err
+	}
+
+	var r config.Resolved
+	if name := s.templateName(); name != "" {
+		r, err = config.ResolveTemplate(name, opts)
+	} else {
+		var path string
+		if path, err = configPath(); err != nil {
+			return r, err
+		}
+		r, err = config.Resolve(path, opts)
+	}
+	if err != nil {
+		return r, fmt.Errorf("failed to load configuration: %v", err)
+	}
+
+	// The session name falls back to a default outside the config package
+	if r.Config.SessionName == "" {
+		value := config.ResolvedValue{Path: "session_name", Value: sessionName(r.Config), Source: config.Source{Kind: "default"}}
+		r.Values = append([]config.ResolvedValue{value}, r.Values...)
+	}
+	return r, nil
+}
+
+// printResolved lists the merged files and every value with its source
+func printResolved(w io.Writer, r config.Resolved) {
+	fmt.Fprintln(w, "Merged files (lowest precedence first):")
+	for _, file := range r.Files {
+		if file == r.Local {
+			fmt.Fprintf(w, "  %s (local overrides)\n", file)
+			continue
+		}
+		fmt.Fprintf(w, "  %s\n", file)
+	}
+	fmt.Fprintln(w)
+
+	tw := tabwriter.NewWriter(w, 0, 4, 2, ' ', 0)
+	for _, value := range r.Values {
+		fmt.Fprintf(tw, "%s\t%v\t# %s\n", value.Path, value.Value, value.Source)
+	}
+	tw.Flush()
+}
+
+// printResolvedJSON prints the resolved config with its sources as JSON
+func printResolvedJSON(w io.Writer, r config.Resolved) error {
+	// The config types only carry yaml tags, so the config is converted
+	// through YAML to keep the field names of the file
+	data, err := yaml.Marshal(r.Config)
+	if err != nil {
+		return err
+	}
+	var cfg map[string]interface{}
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		return err
+	}
+
+	output := struct {
+		Config map[string]interface{} `json:"config"`
+		config.Resolved
+	}{cfg, r}
+
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(output)
+}
@@ -0,0 +1,408 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"github.com/bartosz-skejcik/tmux-setup/internal/importer"
+	"gopkg.in/yaml.v3"
+)
+
+func importCommand() *Command {
+	return &Command{
+		Name:  "import",
+		Short: "Translate the configs of other tools",
+		Subcommands: []*Command{
+			importToolCommand(importSource{
+				tool:      "tmuxinator",
+				kind:      "project",
+				exts:      []string{".yml", ".yaml"},
+				translate: importer.Tmuxinator,
+				dir:       importer.TmuxinatorDir,
+				list:      importer.TmuxinatorProjects,
+			}),
+			importToolCommand(importSource{
+				tool:      "tmuxp",
+				kind:      "session",
+				exts:      []string{".yaml", ".yml", ".json"},
+				translate: importer.Tmuxp,
+				dir:       importer.TmuxpDir,
+				list:      importer.TmuxpSessions,
+			}),
+			importProjectCommand(projectSource{
+				name:  "procfile",
+				files: []string{"Procfile"},
+				entry: "process",
+				translate: func(data []byte, _ string, panes bool) (importer.Result, error) {
+					return importer.Procfile(data, panes)
+				},
+			}),
+			importProjectCommand(projectSource{
+				name:      "compose",
+				files:     importer.ComposeFileNames,
+				entry:     "service",
+				translate: importer.Compose,
+			}),
+		},
+	}
+}
+
+// importSource is a tool whose configs can be imported
+type importSource struct {
+	tool string
+	// kind is what the tool calls a config, e.g. project
+	kind      string
+	exts      []string
+	translate func([]byte) (importer.Result, error)
+	// dir returns the directory of the tool's configs, and list the
+	// config files in it
+	dir  func() (string, error)
+	list func() ([]string, error)
+}
+
+// importFlags select where imported configs are written
+type importFlags struct {
+	template bool
+	force    bool
+	merge    bool
+}
+
+func (f *importFlags) register(flags *flag.FlagSet) {
+	flags.BoolVar(&f.template, "template", false, "Write templates instead of project files")
+	flags.BoolVar(&f.force, "force", false, "Overwrite existing files")
+	flags.BoolVar(&f.merge, "merge", false, "Merge into existing files instead of overwriting them")
+}
+
+func (f *importFlags) check() error {
+	if f.force && f.merge {
+		return usagef("--force and --merge can't be combined")
+	}
+	return nil
+}
+
+func importToolCommand(source importSource) *Command {
+	var target importFlags
+	var all bool
+
+	return &Command{
+		Name:     source.tool,
+		Args:     "<file|name>|--all",
+		Complete: source.complete,
+		Short:    fmt.Sprintf("Import %s %ss as project files or templates", source.tool, source.kind),
+		SetFlags: func(flags *flag.FlagSet) {
+			target.register(flags)
+			flags.BoolVar(&all, "all", false, fmt.Sprintf("Import every %s in the %s directory", source.kind, source.tool))
+		},
+		Run: func(args []string) error {
+			if err := target.check(); err != nil {
+				return err
+			}
+			var files []string
+			switch {
+			case all && len(args) > 0:
+				return usagef("--all doesn't take arguments")
+			case all:
+				var err error
+				if files, err = source.list(); err != nil {
+					return err
+				}
+				if len(files) == 0 {
+					return fmt.Errorf("no %s %ss found", source.tool, source.kind)
+				}
+			case len(args) == 1:
+				file, err := source.find(args[0])
+				if err != nil {
+					return err
+				}
+				files = []string{file}
+			default:
+				return usagef("expected a %s %s or --all", source.tool, source.kind)
+			}
+
+			if len(files) == 1 {
+				return target.importFile(files[0], source.translate)
+			}
+			failed := 0
+			for _, file := range files {
+				if err := target.importFile(file, source.translate); err != nil {
+					fmt.Fprintf(os.Stderr, "Error: %s: %v\n", file, err)
+					failed++
+				}
+			}
+			if failed > 0 {
+				return fmt.Errorf("failed to import %d of %d %ss", failed, len(files), source.kind)
+			}
+			return nil
+		},
+	}
+}
+
+// find returns the config file given by path or by name
+func (s importSource) find(arg string) (string, error) {
+	if _, err := os.Stat(arg); err == nil {
+		return arg, nil
+	}
+	dir, err := s.dir()
+	if err != nil {
+		return "", fmt.Errorf("%s not found", arg)
+	}
+	for _, ext := range s.exts {
+		file := filepath.Join(dir, arg+ext)
+		if _, err := os.Stat(file); err == nil {
+			return file, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found, and no %s %s of that name in %s", arg, s.tool, s.kind, dir)
+}
+
+func (s importSource) complete(args []string) []string {
+	if len(args) > 0 {
+		return nil
+	}
+	files, _ := s.list()
+	names := make([]string, len(files))
+	for i, file := range files {
+		names[i] = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	return names
+}
+
+// importFile translates a file and writes the result, reporting what
+// couldn't be translated
+func (f *importFlags) importFile(file string, translate func([]byte) (importer.Result, error)) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return err
+	}
+	result, err := translate(data)
+	if err != nil {
+		return err
+	}
+	if result.Name == "" {
+		result.Name = strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+	}
+	return f.write(file, result)
+}
+
+// write writes an imported config, or merges it into the existing one
+func (f *importFlags) write(file string, result importer.Result) error {
+	target, err := f.targetPath(result)
+	if err != nil {
+		return err
+	}
+
+	if f.merge && fileExists(target) {
+		data, err := config.MergeFile(target, result.Config)
+		if err != nil {
+			return err
+		}
+		if err := writeFile(target, data, true); err != nil {
+			return err
+		}
+		fmt.Printf("Merged %s into %s\n", file, target)
+	} else {
+		if err := writeConfig(target, result.Config, f.force); err != nil {
+			return err
+		}
+		fmt.Printf("Imported %s to %s\n", file, target)
+	}
+
+	for _, note := range result.Notes {
+		fmt.Printf("  not translated: %s\n", note)
+	}
+	return nil
+}
+
+// targetPath returns where an imported config is written: the template of
+// its name, or the config in the project's root, or else in the current
+// directory
+func (f *importFlags) targetPath(result importer.Result) (string, error) {
+	if f.template {
+		return config.TemplatePath(result.Name)
+	}
+	if result.Root == "" {
+		return config.ConfigFileNames[0], nil
+	}
+
+	root := result.Root
+	if rest, ok := strings.CutPrefix(root, "~"); ok {
+		homeDir, err := os.UserHomeDir()
+		if err != nil {
+			return "", err
+		}
+		root = homeDir + rest
+	}
+	if info, err := os.Stat(root); err != nil || !info.IsDir() {
+		return "", fmt.Errorf("root %s is not a directory, import it with --template", result.Root)
+	}
+	for _, name := range config.ConfigFileNames {
+		if path := filepath.Join(root, name); fileExists(path) {
+			return path, nil
+		}
+	}
+	return filepath.Join(root, config.ConfigFileNames[0]), nil
+}
+
+// projectSource is a file of a project whose entries can be imported as
+// windows, like a Procfile
+type projectSource struct {
+	name string
+	// files are the names the file is looked for by, in order
+	files []string
+	// entry is what the file calls its entries, e.g. process
+	entry string
+	// translate is given the file's path relative to the project
+	translate func(data []byte, file string, panes bool) (importer.Result, error)
+}
+
+func importProjectCommand(source projectSource) *Command {
+	var target importFlags
+	var panes bool
+
+	return &Command{
+		Name:  source.name,
+		Args:  "[file]",
+		Short: fmt.Sprintf("Import a %s as a window per %s into the project next to it", source.files[0], source.entry),
+		SetFlags: func(flags *flag.FlagSet) {
+			target.register(flags)
+			flags.BoolVar(&panes, "panes", false, fmt.Sprintf("Put every %s in a pane of one window", source.entry))
+		},
+		Run: func(args []string) error {
+			if err := target.check(); err != nil {
+				return err
+			}
+			var file string
+			switch len(args) {
+			case 0:
+				if i := slices.IndexFunc(source.files, fileExists); i >= 0 {
+					file = source.files[i]
+				} else {
+					return fmt.Errorf("no %s in the current directory", source.files[0])
+				}
+			case 1:
+				file = args[0]
+			default:
+				return usagef("unexpected arguments: %v", args[1:])
+			}
+
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return err
+			}
+			result, err := source.translate(data, filepath.Base(file), panes)
+			if err != nil {
+				return fmt.Errorf("%s: %v", file, err)
+			}
+			// The config goes next to the file, in the project's root
+			if result.Root, err = filepath.Abs(filepath.Dir(file)); err != nil {
+				return err
+			}
+			result.Name = filepath.Base(result.Root)
+			return target.write(file, result)
+		},
+	}
+}
+
+func fileExists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
+
+// writeConfig writes cfg to path, in the format of its extension
+func writeConfig(path string, cfg config.Config, force bool) error {
+	data, err := config.Marshal(cfg, config.Format(path))
+	if err != nil {
+		return err
+	}
+	return writeFile(path, data, force)
+}
+
+// writeFile writes data to a new file, or replaces it with force
+func writeFile(path string, data []byte, force bool) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !force {
+		flags |= os.O_EXCL
+	}
+	file, err := os.OpenFile(path, flags, 0644)
+	if errors.Is(err, os.ErrExist) {
+		return fmt.Errorf("%s already exists, use --force to overwrite it", path)
+	}
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	_, err = file.Write(data)
+	return err
+}
+
+func exportCommand() *Command {
+	var source sourceFlags
+	var format, output string
+	var force bool
+
+	return &Command{
+		Name:  "export",
+		Short: "Translate the config into the format of another tool",
+		SetFlags: func(flags *flag.FlagSet) {
+			source.register(flags)
+			flags.StringVar(&format, "format", "", "Format to export to: tmuxp")
+			flags.StringVar(&output, "output", "", "Write to a file instead of printing; .json files are written as JSON")
+			flags.BoolVar(&force, "force", false, "Overwrite the output file")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+			if format != "tmuxp" {
+				return usagef("--format must be tmuxp")
+			}
+
+			cfg, _, err := source.load()
+			if err != nil {
+				return err
+			}
+			cfg.SessionName = sessionName(cfg)
+			session, notes := importer.ExportTmuxp(cfg)
+
+			var data []byte
+			if config.Format(output) == "json" {
+				data, err = json.MarshalIndent(session, "", "  ")
+				data = append(data, '\n')
+			} else {
+				var out bytes.Buffer
+				encoder := yaml.NewEncoder(&out)
+				encoder.SetIndent(2)
+				err = encoder.Encode(session)
+				data = out.Bytes()
+			}
+			if err != nil {
+				return err
+			}
+
+			for _, note := range notes {
+				fmt.Fprintf(os.Stderr, "not exported: %s\n", note)
+			}
+			if output == "" {
+				os.Stdout.Write(data)
+				return nil
+			}
+			if err := writeFile(output, data, force); err != nil {
+				return err
+			}
+			fmt.Printf("Exported to %s\n", output)
+			return nil
+		},
+	}
+}
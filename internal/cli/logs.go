@@ -0,0 +1,37 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/hooks"
+)
+
+func logsCommand() *Command {
+	return &Command{
+		Name:  "logs",
+		Args:  "[name|last]",
+		Short: "List hook logs of past runs, or print one",
+		Run: func(args []string) error {
+			switch len(args) {
+			case 0:
+				logs, err := hooks.ListLogs()
+				if err != nil {
+					return fmt.Errorf("failed to list hook logs: %v", err)
+				}
+				for _, name := range logs {
+					fmt.Println(name)
+				}
+				return nil
+			case 1:
+				data, err := hooks.ReadLog(args[0])
+				if err != nil {
+					return fmt.Errorf("failed to read hook log: %v", err)
+				}
+				os.Stdout.Write(data)
+				return nil
+			}
+			return usagef("expected at most one log name")
+		},
+	}
+}
@@ -0,0 +1,194 @@
+package cli
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+)
+
+func planCommand() *Command {
+	var source sourceFlags
+
+	return &Command{
+		Name:     "plan",
+		Short:    "Show the session start would create, without running anything",
+		SetFlags: source.register,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+
+			cfg, _, err := source.load()
+			if err != nil {
+				return err
+			}
+			printPlan(os.Stdout, cfg)
+			return nil
+		},
+	}
+}
+
+func validateCommand() *Command {
+	var source sourceFlags
+
+	return &Command{
+		Name:     "validate",
+		Short:    "Check the config for errors",
+		SetFlags: source.register,
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+
+			path, err := source.path()
+			if err != nil {
+				return err
+			}
+
+			opts, err := source.options()
+			if err != nil {
+				return err
+			}
+
+			diagnostics, err := config.Validate(path, opts)
+			if err != nil {
+				return err
+			}
+			for _, d := range diagnostics {
+				fmt.Println(d)
+			}
+
+			if config.HasErrors(diagnostics) {
+				return fmt.Errorf("%s is invalid", path)
+			}
+			fmt.Printf("%s: OK\n", path)
+			return nil
+		},
+	}
+}
+
+func schemaCommand() *Command {
+	return &Command{
+		Name:  "schema",
+		Short: "Print the JSON Schema of tmux.conf.yml",
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+
+			data, err := json.MarshalIndent(config.Schema(), "", "  ")
+			if err != nil {
+				return err
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+}
+
+// printPlan describes the session, windows and panes cfg would create
+func printPlan(w io.Writer, cfg config.Config) {
+	focus := cfg.Focus()
+	if focus == 0 {
+		focus = 1
+	}
+	fmt.Fprintf(w, "Session %s (focus window %d)\n", sessionName(cfg), focus)
+
+	if len(cfg.Dependencies) > 0 {
+		fmt.Fprintf(w, "  dependencies: %s\n", strings.Join(cfg.Dependencies, ", "))
+	}
+	printField(w, "  ", "directory", cfg.Defaults.Directory)
+	printField(w, "  ", "pre_command", cfg.Defaults.PreCommand)
+	printField(w, "  ", "post_command", cfg.Defaults.PostCommand)
+	printEnv(w, "  ", cfg.Env)
+
+	events := make([]string, 0, len(cfg.TmuxHooks))
+	for event := range cfg.TmuxHooks {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	for _, event := range events {
+		printField(w, "  ", "on "+event, cfg.TmuxHooks[event])
+	}
+
+	for i, window := range cfg.Windows {
+		name := window.Name
+		if name == "" {
+			name = fmt.Sprintf("window-%d", i+1)
+		}
+		fmt.Fprintf(w, "\nWindow %d: %s\n", i+1, name)
+		printField(w, "  ", "directory", window.Directory)
+		printField(w, "  ", "git_branch", window.GitBranch)
+		if window.Layout != nil {
+			printField(w, "  ", "layout", fmt.Sprint(window.Layout))
+		}
+		printField(w, "  ", "pre_command", window.PreCommand)
+		printField(w, "  ", "initial_command", window.InitialCommand)
+		printField(w, "  ", "post_command", window.PostCommand)
+		printEnv(w, "  ", window.Env)
+		printCommandCondition(w, "  ", window.When)
+		printForEachCommand(w, "  ", window.ForEach)
+
+		for j, pane := range window.Panes {
+			if pane.Name != "" {
+				fmt.Fprintf(w, "  Pane %d: %s\n", j+1, pane.Name)
+			} else {
+				fmt.Fprintf(w, "  Pane %d\n", j+1)
+			}
+			printField(w, "    ", "directory", pane.Directory)
+			printField(w, "    ", "pre_command", pane.PreCommand)
+			printField(w, "    ", "initial_command", pane.InitialCommand)
+			printField(w, "    ", "post_command", pane.PostCommand)
+			printEnv(w, "    ", pane.Env)
+			if pane.RefreshInterval > 0 {
+				printField(w, "    ", "refresh_interval", fmt.Sprintf("%ds", pane.RefreshInterval))
+			}
+			printCommandCondition(w, "    ", pane.When)
+			printForEachCommand(w, "    ", pane.ForEach)
+		}
+	}
+
+	if len(cfg.Skipped) > 0 {
+		fmt.Fprintln(w, "\nSkipped:")
+		for _, skip := range cfg.Skipped {
+			fmt.Fprintf(w, "  %s\n", skip)
+		}
+	}
+}
+
+// printForEachCommand notes a for_each command, which plan doesn't run, so
+// the element is shown unexpanded
+func printForEachCommand(w io.Writer, indent string, forEach *config.ForEach) {
+	if forEach != nil && forEach.Command != "" {
+		fmt.Fprintf(w, "%sfor_each command: %s (not run by plan)\n", indent, forEach.Command)
+	}
+}
+
+// printCommandCondition notes a when command, which plan doesn't run
+func printCommandCondition(w io.Writer, indent string, when *config.Condition) {
+	if when != nil && when.Command != "" {
+		fmt.Fprintf(w, "%swhen command: %s (not checked by plan)\n", indent, when.Command)
+	}
+}
+
+func printField(w io.Writer, indent, name, value string) {
+	if value != "" {
+		fmt.Fprintf(w, "%s%s: %s\n", indent, name, value)
+	}
+}
+
+func printEnv(w io.Writer, indent string, env map[string]string) {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		fmt.Fprintf(w, "%senv %s=%s\n", indent, key, env[key])
+	}
+}
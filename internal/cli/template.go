@@ -0,0 +1,166 @@
+package cli
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"github.com/bartosz-skejcik/tmux-setup/internal/detect"
+	"github.com/bartosz-skejcik/tmux-setup/internal/wizard"
+)
+
+func wizardCommand() *Command {
+	var createTemplate string
+
+	return &Command{
+		Name:  "wizard",
+		Short: "Create a tmux.conf.yml interactively",
+		SetFlags: func(flags *flag.FlagSet) {
+			flags.StringVar(&createTemplate, "create-template", "", "Create a template with the given name instead")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+			if createTemplate != "" {
+				wizard.StartTemplateCreation(createTemplate)
+				return nil
+			}
+			wizard.Start()
+			return nil
+		},
+	}
+}
+
+func initCommand() *Command {
+	var force bool
+
+	return &Command{
+		Name:  "init",
+		Short: "Propose a tmux.conf.yml for the project in the current directory",
+		SetFlags: func(flags *flag.FlagSet) {
+			flags.BoolVar(&force, "force", false, "Propose a config even if the directory has one")
+		},
+		Run: func(args []string) error {
+			if len(args) > 0 {
+				return usagef("unexpected arguments: %v", args)
+			}
+			dir, err := os.Getwd()
+			if err != nil {
+				return err
+			}
+			if !force {
+				for _, name := range config.ConfigFileNames {
+					if _, err := os.Stat(filepath.Join(dir, name)); err == nil {
+						return fmt.Errorf("%s already exists, use --force to replace it", name)
+					}
+				}
+			}
+
+			proposal, err := detect.Project(dir, globals.settings.EditorCommand())
+			if err != nil {
+				return err
+			}
+			if len(proposal.Findings) == 0 {
+				fmt.Println("Detected nothing, proposing an editor window only")
+			} else {
+				fmt.Printf("Detected %s\n", strings.Join(proposal.Findings, "; "))
+			}
+			proposal.Config.SessionName = globals.settings.SessionNameFor(dir)
+			wizard.Review(proposal.Config)
+			return nil
+		},
+	}
+}
+
+func templateCommand() *Command {
+	return &Command{
+		Name:  "template",
+		Short: "Manage templates in ~/.config/tmux-setup/templates/",
+		Subcommands: []*Command{
+			{
+				Name:  "list",
+				Short: "List available templates",
+				Run: func(args []string) error {
+					names, err := config.ListTemplates()
+					if err != nil {
+						return err
+					}
+					for _, name := range names {
+						fmt.Println(name)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "create",
+				Args:  "<name>",
+				Short: "Create a template with the wizard",
+				Run: func(args []string) error {
+					name, err := templateArg(args)
+					if err != nil {
+						return err
+					}
+					wizard.StartTemplateCreation(name)
+					return nil
+				},
+			},
+			{
+				Name:     "show",
+				Args:     "<name>",
+				Complete: completeTemplateArg,
+				Short:    "Print a template",
+				Run: func(args []string) error {
+					name, err := templateArg(args)
+					if err != nil {
+						return err
+					}
+					path, err := config.TemplatePath(name)
+					if err != nil {
+						return err
+					}
+					data, err := os.ReadFile(path)
+					if err != nil {
+						return err
+					}
+					os.Stdout.Write(data)
+					return nil
+				},
+			},
+			{
+				Name:     "delete",
+				Args:     "<name>",
+				Complete: completeTemplateArg,
+				Short:    "Delete a template",
+				Run: func(args []string) error {
+					name, err := templateArg(args)
+					if err != nil {
+						return err
+					}
+					path, err := config.TemplatePath(name)
+					if err != nil {
+						return err
+					}
+					return os.Remove(path)
+				},
+			},
+		},
+	}
+}
+
+func templateArg(args []string) (string, error) {
+	if len(args) != 1 {
+		return "", usagef("expected a template name")
+	}
+	return args[0], nil
+}
+
+func completeTemplateArg(args []string) []string {
+	if len(args) == 0 {
+		return completeTemplates()
+	}
+	return nil
+}
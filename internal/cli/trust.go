@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"github.com/bartosz-skejcik/tmux-setup/internal/trust"
+)
+
+func allowCommand() *Command {
+	return &Command{
+		Name:  "allow",
+		Args:  "[path]",
+		Short: "Approve the commands of a project config",
+		Run: func(args []string) error {
+			return recordTrust("allow", trust.Allow, args)
+		},
+	}
+}
+
+func denyCommand() *Command {
+	return &Command{
+		Name:  "deny",
+		Args:  "[path]",
+		Short: "Block the commands of a project config",
+		Run: func(args []string) error {
+			return recordTrust("deny", trust.Deny, args)
+		},
+	}
+}
+
+func trustCommand() *Command {
+	return &Command{
+		Name:  "trust",
+		Short: "Inspect the trust store",
+		Subcommands: []*Command{
+			{
+				Name:  "list",
+				Short: "List approved and denied project configs",
+				Run: func(args []string) error {
+					entries, err := trust.List()
+					if err != nil {
+						return fmt.Errorf("failed to read trust store: %v", err)
+					}
+					for _, entry := range entries {
+						status := entry.Status
+						// Configs that no longer load are checked on their own
+						files, _ := config.ProjectFiles(entry.Path, config.Options{})
+						if current, err := trust.Check(entry.Path, files); err == nil && current == trust.Changed {
+							status = current
+						}
+						fmt.Printf("%-8s %s\n", status, entry.Path)
+					}
+					return nil
+				},
+			},
+		},
+	}
+}
+
+// checkTrust fails unless the config file at path, with the files it reads,
+// has been allowed or is in one of the trusted paths, listing the commands
+// it would run
+func checkTrust(path string, files []string, cfg config.Config) error {
+	if globals.settings.IsTrusted(path) {
+		return nil
+	}
+
+	status, err := trust.Check(path, files)
+	if err != nil {
+		return fmt.Errorf("failed to check trust for %s: %v", path, err)
+	}
+
+	switch status {
+	case trust.Allowed:
+		return nil
+	case trust.Denied:
+		return fmt.Errorf("%s is denied, run 'tmux-setup allow' to approve it", path)
+	case trust.Changed:
+		fmt.Fprintf(os.Stderr, "%s or a file it reads has changed since it was last approved.\n", path)
+	default:
+		fmt.Fprintf(os.Stderr, "%s is not trusted yet.\n", path)
+	}
+
+	if len(files) > 1 {
+		fmt.Fprintln(os.Stderr, "It reads:")
+		for _, file := range files[1:] {
+			fmt.Fprintf(os.Stderr, "  %s\n", file)
+		}
+	}
+	fmt.Fprintln(os.Stderr, "It would run the following commands:")
+	for _, command := range trust.Commands(cfg) {
+		fmt.Fprintf(os.Stderr, "  %s\n", command)
+	}
+	return fmt.Errorf("run 'tmux-setup allow' to approve it or 'tmux-setup deny' to block it")
+}
+
+// recordTrust allows or denies the config file given in args, or the
+// selected one, together with the files it reads: includes, the local
+// override file and env files
+func recordTrust(action string, record func(string, []string) error, args []string) error {
+	var path string
+	switch len(args) {
+	case 0:
+		var err error
+		if path, err = configPath(); err != nil {
+			return err
+		}
+	case 1:
+		path = args[0]
+	default:
+		return usagef("expected at most one path")
+	}
+
+	files, err := config.ProjectFiles(path, config.Options{})
+	if err != nil {
+		return fmt.Errorf("failed to %s %s: %v", action, path, err)
+	}
+	if err := record(path, files); err != nil {
+		return fmt.Errorf("failed to %s %s: %v", action, path, err)
+	}
+	fmt.Printf("%s: %s\n", action, path)
+	for _, file := range files[1:] {
+		fmt.Printf("  with %s\n", file)
+	}
+	return nil
+}
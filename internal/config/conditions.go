@@ -0,0 +1,156 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Condition decides whether a window or pane is created. Every check that
+// is set has to pass.
+type Condition struct {
+	FileExists string            `yaml:"file_exists,omitempty" desc:"Path that must exist, relative to the config file"`
+	EnvSet     string            `yaml:"env_set,omitempty" desc:"Environment variable that must be set and non-empty"`
+	EnvUnset   string            `yaml:"env_unset,omitempty" desc:"Environment variable that must be unset or empty"`
+	EnvEquals  map[string]string `yaml:"env_equals,omitempty" desc:"Environment variables that must have the given values"`
+	Hostname   string            `yaml:"hostname,omitempty" desc:"Glob the host name must match, e.g. gpu-*"`
+	Command    string            `yaml:"command,omitempty" desc:"Shell command that must succeed, run in the config file's directory"`
+	GitBranch  string            `yaml:"git_branch,omitempty" desc:"Glob the current git branch of the project must match"`
+}
+
+// Skip records a window or pane left out because its when condition failed
+type Skip struct {
+	Window string // name of the window, or "window N"
+	Pane   string // name of the pane, or "pane N"; empty for windows
+	Reason string
+}
+
+func (s Skip) String() string {
+	if s.Pane != "" {
+		return fmt.Sprintf("%s, %s: %s", s.Window, s.Pane, s.Reason)
+	}
+	return fmt.Sprintf("%s: %s", s.Window, s.Reason)
+}
+
+// ApplyConditions removes the windows and panes whose when condition fails
+// and records them in cfg.Skipped. Paths are relative to baseDir. Command
+// conditions only run with runCommands, and are assumed to pass otherwise.
+func ApplyConditions(cfg Config, baseDir string, runCommands bool) (Config, error) {
+	var windows []WindowConfig
+	for i, window := range cfg.Windows {
+		windowLabel := window.Name
+		if windowLabel == "" {
+			windowLabel = fmt.Sprintf("window %d", i+1)
+		}
+
+		reason, err := window.When.check(baseDir, runCommands)
+		if err != nil {
+			return cfg, fmt.Errorf("%s: %v", windowLabel, err)
+		}
+		if reason != "" {
+			cfg.Skipped = append(cfg.Skipped, Skip{Window: windowLabel, Reason: reason})
+			continue
+		}
+
+		var panes []PaneConfig
+		for j, pane := range window.Panes {
+			paneLabel := pane.Name
+			if paneLabel == "" {
+				paneLabel = fmt.Sprintf("pane %d", j+1)
+			}
+
+			reason, err := pane.When.check(baseDir, runCommands)
+			if err != nil {
+				return cfg, fmt.Errorf("%s, %s: %v", windowLabel, paneLabel, err)
+			}
+			if reason != "" {
+				cfg.Skipped = append(cfg.Skipped, Skip{Window: windowLabel, Pane: paneLabel, Reason: reason})
+				continue
+			}
+			panes = append(panes, pane)
+		}
+		window.Panes = panes
+		windows = append(windows, window)
+	}
+	cfg.Windows = windows
+	return cfg, nil
+}
+
+// check returns why the condition doesn't hold, or "" if it does
+func (c *Condition) check(baseDir string, runCommands bool) (string, error) {
+	if c == nil {
+		return "", nil
+	}
+
+	if c.FileExists != "" {
+		path := c.FileExists
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(baseDir, path)
+		}
+		if _, err := os.Stat(path); err != nil {
+			return fmt.Sprintf("file %s does not exist", c.FileExists), nil
+		}
+	}
+
+	if c.EnvSet != "" && os.Getenv(c.EnvSet) == "" {
+		return fmt.Sprintf("$%s is not set", c.EnvSet), nil
+	}
+	if c.EnvUnset != "" && os.Getenv(c.EnvUnset) != "" {
+		return fmt.Sprintf("$%s is set", c.EnvUnset), nil
+	}
+
+	names := make([]string, 0, len(c.EnvEquals))
+	for name := range c.EnvEquals {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if value := os.Getenv(name); value != c.EnvEquals[name] {
+			return fmt.Sprintf("$%s is %q, not %q", name, value, c.EnvEquals[name]), nil
+		}
+	}
+
+	if c.Hostname != "" {
+		hostname, err := os.Hostname()
+		if err != nil {
+			return "", err
+		}
+		if !matchGlob(c.Hostname, hostname) {
+			return fmt.Sprintf("host name %s does not match %s", hostname, c.Hostname), nil
+		}
+	}
+
+	if c.GitBranch != "" {
+		output, err := exec.Command("git", "-C", baseDir, "branch", "--show-current").Output()
+		if err != nil {
+			return "not in a git repository", nil
+		}
+		branch := strings.TrimSpace(string(output))
+		if branch == "" {
+			return "git HEAD is detached", nil
+		}
+		if !matchGlob(c.GitBranch, branch) {
+			return fmt.Sprintf("git branch %s does not match %s", branch, c.GitBranch), nil
+		}
+	}
+
+	if c.Command != "" && runCommands {
+		cmd := exec.Command("sh", "-c", c.Command)
+		cmd.Dir = baseDir
+		if err := cmd.Run(); err != nil {
+			return fmt.Sprintf("command %q failed: %v", c.Command, err), nil
+		}
+	}
+
+	return "", nil
+}
+
+// matchGlob reports whether value matches pattern. Unlike filepath.Match,
+// * also matches slashes, so feature/* matches any feature branch.
+func matchGlob(pattern, value string) bool {
+	matched, err := filepath.Match(strings.ReplaceAll(pattern, "/", "\x00"), strings.ReplaceAll(value, "/", "\x00"))
+	return err == nil && matched
+}
@@ -0,0 +1,133 @@
+package config
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestMatchGlob(t *testing.T) {
+	tests := []struct {
+		pattern string
+		value   string
+		want    bool
+	}{
+		{"main", "main", true},
+		{"main", "master", false},
+		{"gpu-*", "gpu-01", true},
+		{"gpu-*", "cpu-01", false},
+		{"gpu-??", "gpu-01", true},
+		{"[ab]-*", "b-box", true},
+		// * crosses slashes, unlike filepath.Match
+		{"feature/*", "feature/login", true},
+		{"feature/*", "feature/auth/login", true},
+		{"*", "release/1.2", true},
+		{"*/hotfix", "team/hotfix", true},
+		{"feature/*", "bugfix/login", false},
+		{"feature/*", "feature", false},
+		// The slash is still matched literally
+		{"feature/?", "feature/x", true},
+		{"feature?x", "feature/x", true},
+		{"invalid[", "invalid[", false},
+	}
+
+	for _, test := range tests {
+		if got := matchGlob(test.pattern, test.value); got != test.want {
+			t.Errorf("matchGlob(%q, %q) = %t, want %t", test.pattern, test.value, got, test.want)
+		}
+	}
+}
+
+func TestApplyConditions(t *testing.T) {
+	dir := t.TempDir()
+	writeFile(t, dir, "docker-compose.yml", "services: {}\n")
+	t.Setenv("TMUX_SETUP_TEST_SET", "1")
+	t.Setenv("TMUX_SETUP_TEST_EMPTY", "")
+	t.Setenv("TMUX_SETUP_TEST_ENV", "dev")
+	hostname, err := os.Hostname()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name        string
+		when        Condition
+		runCommands bool
+		reason      string // "" when the window is kept
+	}{
+		{name: "no checks", when: Condition{}},
+		{name: "file exists", when: Condition{FileExists: "docker-compose.yml"}},
+		{name: "file is missing", when: Condition{FileExists: "Procfile"}, reason: "file Procfile does not exist"},
+		{name: "absolute path", when: Condition{FileExists: dir}},
+		{name: "env set", when: Condition{EnvSet: "TMUX_SETUP_TEST_SET"}},
+		{name: "empty env isn't set", when: Condition{EnvSet: "TMUX_SETUP_TEST_EMPTY"}, reason: "$TMUX_SETUP_TEST_EMPTY is not set"},
+		{name: "env unset", when: Condition{EnvUnset: "TMUX_SETUP_TEST_EMPTY"}},
+		{name: "env unset but set", when: Condition{EnvUnset: "TMUX_SETUP_TEST_SET"}, reason: "$TMUX_SETUP_TEST_SET is set"},
+		{name: "env equals", when: Condition{EnvEquals: map[string]string{"TMUX_SETUP_TEST_ENV": "dev", "TMUX_SETUP_TEST_SET": "1"}}},
+		{
+			name:   "env differs",
+			when:   Condition{EnvEquals: map[string]string{"TMUX_SETUP_TEST_SET": "1", "TMUX_SETUP_TEST_ENV": "prod"}},
+			reason: `$TMUX_SETUP_TEST_ENV is "dev", not "prod"`,
+		},
+		{name: "hostname", when: Condition{Hostname: hostname}},
+		{name: "hostname glob", when: Condition{Hostname: "*"}},
+		{name: "command not run", when: Condition{Command: "false"}},
+		{name: "command succeeds", when: Condition{Command: "test -f docker-compose.yml"}, runCommands: true},
+		{name: "command fails", when: Condition{Command: "false"}, runCommands: true, reason: `command "false" failed: exit status 1`},
+		{
+			name:   "every check has to pass",
+			when:   Condition{FileExists: "docker-compose.yml", EnvSet: "TMUX_SETUP_TEST_EMPTY"},
+			reason: "$TMUX_SETUP_TEST_EMPTY is not set",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			when := test.when
+			cfg := Config{Windows: []WindowConfig{
+				{Name: "editor"},
+				{When: &when, Panes: []PaneConfig{{InitialCommand: "docker compose up"}}},
+				{Name: "shell", Panes: []PaneConfig{{InitialCommand: "zsh"}, {Name: "logs", When: &when}}},
+			}}
+
+			got, err := ApplyConditions(cfg, dir, test.runCommands)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			want := cfg.Windows
+			var skipped []Skip
+			if test.reason != "" {
+				want = []WindowConfig{
+					cfg.Windows[0],
+					{Name: "shell", Panes: []PaneConfig{{InitialCommand: "zsh"}}},
+				}
+				skipped = []Skip{
+					{Window: "window 2", Reason: test.reason},
+					{Window: "shell", Pane: "logs", Reason: test.reason},
+				}
+			}
+			if !reflect.DeepEqual(got.Windows, want) {
+				t.Errorf("windows =\n%+v\nwant\n%+v", got.Windows, want)
+			}
+			if !reflect.DeepEqual(got.Skipped, skipped) {
+				t.Errorf("skipped = %v, want %v", got.Skipped, skipped)
+			}
+		})
+	}
+}
+
+func TestApplyConditionsGitBranch(t *testing.T) {
+	// The temporary directory isn't a git repository
+	dir := t.TempDir()
+	cfg := Config{Windows: []WindowConfig{{Name: "deploy", When: &Condition{GitBranch: "release/*"}}}}
+
+	got, err := ApplyConditions(cfg, dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Skip{{Window: "deploy", Reason: "not in a git repository"}}
+	if len(got.Windows) != 0 || !reflect.DeepEqual(got.Skipped, want) {
+		t.Errorf("ApplyConditions() = %+v, skipped %v, want %v", got.Windows, got.Skipped, want)
+	}
+}
@@ -1,104 +1,353 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
 
-// Configuration struct for the YAML config file
+// Configuration struct for the YAML config file. The desc tags document the
+// fields in the generated JSON Schema.
 type Config struct {
-	SessionName  string         `yaml:"session_name"`
-	FocusWindow  int            `yaml:"focus_window"`
-	Defaults     GlobalDefaults `yaml:"defaults"`
-	Dependencies []string       `yaml:"dependencies"`
-	Windows      []WindowConfig `yaml:"windows"`
-	Template     string         `yaml:"template,omitempty"`
+	SessionName  string         `yaml:"session_name" desc:"Name of the tmux session to create"`
+	FocusWindow  *int           `yaml:"focus_window" desc:"Index of the window to focus when attaching (1-based)"`
+	Defaults     GlobalDefaults `yaml:"defaults" desc:"Defaults applied to all windows and panes"`
+	Dependencies []string       `yaml:"dependencies" desc:"Commands that must be installed for the session to start"`
+	Windows      []WindowConfig `yaml:"windows" desc:"Windows to create in the session"`
+	Template     string         `yaml:"template,omitempty" desc:"Name of a template in ~/.config/tmux-setup/templates to merge with"`
+	Extends      StringList     `yaml:"extends,omitempty" desc:"Templates to merge with, in order, before template"`
+	Include      StringList     `yaml:"include,omitempty" desc:"Fragment files or globs, relative to this file, merged on top of it in order" interpolate:"false"`
+	// TmuxHooks maps tmux server events to shell commands or
+	// tmux-setup callbacks (see TmuxHookCallbacks)
+	TmuxHooks map[string]string `yaml:"tmux_hooks,omitempty" desc:"Shell commands or @callbacks bound to tmux server events"`
+	EnvFile   []string          `yaml:"env_file,omitempty" desc:"Dotenv files providing variables for ${VAR} interpolation" interpolate:"false"`
+	Env       map[string]string `yaml:"env,omitempty" desc:"Environment variables set for the whole session"`
+	// Params are declared by templates, TemplateParams give their values
+	Params         []TemplateParam        `yaml:"params,omitempty" desc:"Inputs of a parameterised template"`
+	TemplateParams map[string]interface{} `yaml:"template_params,omitempty" desc:"Values for the parameters of the template"`
+	// Profiles are interpolated once they are applied, see ApplyProfiles
+	Profiles map[string]Profile `yaml:"profiles,omitempty" desc:"Named variants of the session, selected with --profile" interpolate:"false"`
+	// Presets are merged below the windows and panes that use them
+	PanePresets   map[string]PaneConfig   `yaml:"pane_presets,omitempty" desc:"Reusable panes, referenced with use" interpolate:"false"`
+	WindowPresets map[string]WindowConfig `yaml:"window_presets,omitempty" desc:"Reusable windows, referenced with use" interpolate:"false"`
+	// Skipped lists the windows and panes left out by their when conditions
+	Skipped []Skip `yaml:"-"`
+}
+
+// StringList is a list of strings that can also be written as a single string
+type StringList []string
+
+// UnmarshalYAML accepts a scalar or a sequence of scalars
+func (l *StringList) UnmarshalYAML(node *yaml.Node) error {
+	if node.Kind == yaml.ScalarNode {
+		*l = StringList{node.Value}
+		return nil
+	}
+	var list []string
+	if err := node.Decode(&list); err != nil {
+		return err
+	}
+	*l = list
+	return nil
+}
+
+// Parents returns the templates the configuration is based on, in merge order
+func (c Config) Parents() []string {
+	parents := append([]string{}, c.Extends...)
+	if c.Template != "" {
+		parents = append(parents, c.Template)
+	}
+	return parents
+}
+
+// Options controls how a configuration file is resolved
+type Options struct {
+	// Set holds template parameter values given with --set key=value. They
+	// override template_params from the config file.
+	Set map[string]interface{}
+	// Profiles are applied in order on top of the configuration
+	Profiles []string
+	// RunCommands runs the commands of when conditions and for_each. Without
+	// it conditions are assumed to pass and for_each elements are kept
+	// unexpanded, so that untrusted configs run nothing.
+	RunCommands bool
+}
+
+// TmuxHookEvents lists the tmux events that can be bound in tmux_hooks
+var TmuxHookEvents = []string{
+	"session-closed",
+	"client-attached",
+	"client-detached",
+	"pane-died",
+	"window-linked",
+}
+
+// LayoutPresets lists the layout names tmux accepts for select-layout
+var LayoutPresets = []string{
+	"even-horizontal",
+	"even-vertical",
+	"main-horizontal",
+	"main-horizontal-mirrored",
+	"main-vertical",
+	"main-vertical-mirrored",
+	"tiled",
+}
+
+// TmuxHookCallbacks lists the tmux-setup callbacks a tmux hook can refer to.
+// A callback is written with a leading "@", e.g. "@post_command".
+var TmuxHookCallbacks = []string{
+	"pre_command",
+	"post_command",
 }
 
 type GlobalDefaults struct {
-	Directory      string `yaml:"directory"`
-	InitialCommand string `yaml:"initial_command"`
-	PreCommand     string `yaml:"pre_command,omitempty"`
-	PostCommand    string `yaml:"post_command,omitempty"`
+	Directory      string `yaml:"directory" desc:"Directory for all windows and panes unless overridden"`
+	InitialCommand string `yaml:"initial_command" desc:"Command for all windows and panes unless overridden"`
+	PreCommand     string `yaml:"pre_command,omitempty" desc:"Command to run before the session starts"`
+	PostCommand    string `yaml:"post_command,omitempty" desc:"Command to run after the session is closed"`
 }
 
 type WindowConfig struct {
-	Name           string       `yaml:"name"`
-	Directory      string       `yaml:"directory"`
-	InitialCommand string       `yaml:"initial_command"`
-	Layout         interface{}  `yaml:"layout"` // Can be string or LayoutConfig
-	GitBranch      string       `yaml:"git_branch"`
-	Panes          []PaneConfig `yaml:"panes"`
-	PreCommand     string       `yaml:"pre_command,omitempty"`
-	PostCommand    string       `yaml:"post_command,omitempty"`
+	Name           string            `yaml:"name" desc:"Name of the window"`
+	Use            string            `yaml:"use,omitempty" desc:"Name of a window preset to start from"`
+	Directory      string            `yaml:"directory" desc:"Directory of the window, relative to defaults.directory"`
+	InitialCommand string            `yaml:"initial_command" desc:"Command to run in the window"`
+	Layout         interface{}       `yaml:"layout" desc:"Layout preset name or custom layout"` // Can be string or LayoutConfig
+	GitBranch      string            `yaml:"git_branch" desc:"Git branch to check out in the window's directory"`
+	Panes          []PaneConfig      `yaml:"panes" desc:"Panes to create in the window"`
+	PreCommand     string            `yaml:"pre_command,omitempty" desc:"Command to run before the window is created"`
+	PostCommand    string            `yaml:"post_command,omitempty" desc:"Command to run after the window is created"`
+	Env            map[string]string `yaml:"env,omitempty" desc:"Environment variables for the window's panes, on top of the session's"`
+	When           *Condition        `yaml:"when,omitempty" desc:"Create the window only if these conditions hold"`
+	ForEach        *ForEach          `yaml:"for_each,omitempty" desc:"Create a copy of the window per item, see {{ .Item }}, {{ .Name }} and {{ .Index }}"`
+	// Merge directives, see MergeConfigs
+	Patch       string `yaml:"$patch,omitempty" desc:"How to merge with the window of the same name in the template" enum:"merge,replace,delete,append"`
+	InsertAfter string `yaml:"insert_after,omitempty" desc:"Name of the window to place this window after when merging"`
 }
 
 type PaneConfig struct {
-	Directory       string `yaml:"directory"`
-	InitialCommand  string `yaml:"initial_command"`
-	RefreshInterval int    `yaml:"refresh_interval,omitempty"`
-	PreCommand      string `yaml:"pre_command,omitempty"`
-	PostCommand     string `yaml:"post_command,omitempty"`
+	Name            string            `yaml:"name,omitempty" desc:"Name used to match the pane when merging with a template"`
+	Use             string            `yaml:"use,omitempty" desc:"Name of a pane preset to start from"`
+	Directory       string            `yaml:"directory" desc:"Directory of the pane, relative to the window's directory"`
+	InitialCommand  string            `yaml:"initial_command" desc:"Command to run in the pane"`
+	RefreshInterval int               `yaml:"refresh_interval,omitempty" desc:"Seconds between re-runs of initial_command (0 disables)"`
+	PreCommand      string            `yaml:"pre_command,omitempty" desc:"Command to run before the pane is created"`
+	PostCommand     string            `yaml:"post_command,omitempty" desc:"Command to run after the pane is created"`
+	Env             map[string]string `yaml:"env,omitempty" desc:"Environment variables for the pane, on top of the window's"`
+	When            *Condition        `yaml:"when,omitempty" desc:"Create the pane only if these conditions hold"`
+	ForEach         *ForEach          `yaml:"for_each,omitempty" desc:"Create a copy of the pane per item, see {{ .Item }}, {{ .Name }} and {{ .Index }}"`
+	Patch           string            `yaml:"$patch,omitempty" desc:"How to merge with the matching pane in the template" enum:"merge,replace,delete,append"`
+}
+
+// Profile is a variant of the session that is merged on top of the config
+type Profile struct {
+	Dependencies []string          `yaml:"dependencies,omitempty" desc:"Additional commands that must be installed"`
+	Windows      []WindowConfig    `yaml:"windows,omitempty" desc:"Windows to add or change, merged like a template"`
+	Env          map[string]string `yaml:"env,omitempty" desc:"Additional environment variables for the session"`
 }
 
 type LayoutConfig struct {
-	Direction string       `yaml:"direction"`
-	Panes     []PaneLayout `yaml:"panes"`
+	Direction string       `yaml:"direction" desc:"Direction panes are split in" enum:"horizontal,vertical"`
+	Panes     []PaneLayout `yaml:"panes" desc:"Sizes of the panes, in order"`
 }
 
 type PaneLayout struct {
-	Width  string `yaml:"width,omitempty"`
-	Height string `yaml:"height,omitempty"`
+	Width  string `yaml:"width,omitempty" desc:"Width of the pane, e.g. 30%"`
+	Height string `yaml:"height,omitempty" desc:"Height of the pane, e.g. 50%"`
+}
+
+// Focus returns the index of the window to focus, 0 if it isn't set
+func (c Config) Focus() int {
+	if c.FocusWindow == nil {
+		return 0
+	}
+	return *c.FocusWindow
+}
+
+// MergeEnv returns the variables of all maps, later maps overriding earlier ones
+func MergeEnv(envs ...map[string]string) map[string]string {
+	merged := map[string]string{}
+	for _, env := range envs {
+		for key, value := range env {
+			merged[key] = value
+		}
+	}
+	return merged
 }
 
-// FindConfigFile locates the config file in current or parent directories
+// FindConfigFile locates the config file in current or parent directories.
+// In a directory with several, the first of ConfigFileNames wins.
 func FindConfigFile() string {
 	currentDir, _ := os.Getwd()
 	for currentDir != "/" {
-		configPath := filepath.Join(currentDir, "tmux.conf.yml")
-		if _, err := os.Stat(configPath); err == nil {
-			return configPath
+		for _, name := range ConfigFileNames {
+			configPath := filepath.Join(currentDir, name)
+			if _, err := os.Stat(configPath); err == nil {
+				return configPath
+			}
 		}
 		currentDir = filepath.Dir(currentDir)
 	}
 	return ""
 }
 
-// Load YAML configuration file
+// LocalConfigFile returns the user-private override file next to the config
+// file at path, e.g. tmux.conf.local.yml, or "" if there is none
+func LocalConfigFile(path string) string {
+	ext := filepath.Ext(path)
+	localPath := strings.TrimSuffix(path, ext) + ".local" + ext
+	if _, err := os.Stat(localPath); err != nil {
+		return ""
+	}
+	return localPath
+}
+
+// Load a YAML, TOML or JSON configuration file
 func Load(path string) (Config, error) {
+	return LoadWithOptions(path, Options{})
+}
+
+// LoadWithOptions loads a configuration file, resolving its template with
+// the given options
+func LoadWithOptions(path string, opts Options) (Config, error) {
+	config, _, err := loadFile(path, opts)
+	return config, err
+}
+
+// ProjectFiles returns the files of the project the configuration file at
+// path is read from: the file itself, its includes, its local override file
+// and that file's includes, and the env files. Templates and presets are
+// the user's own and aren't included.
+func ProjectFiles(path string, opts Options) ([]string, error) {
+	_, l, err := loadFile(path, opts)
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, layer := range l.layers {
+		switch layer.kind {
+		case "project", "include", "local":
+			files = append(files, layer.file)
+		}
+	}
+	return append(files, l.envFiles...), nil
+}
+
+// loadFile loads a configuration file and returns the loader that
+// resolved it, which knows the layers it was merged from
+func loadFile(path string, opts Options) (Config, *loader, error) {
 	var config Config
-	data, err := os.ReadFile(path)
+	data, err := readConfig(path)
 	if err != nil {
-		return config, err
+		return config, nil, err
 	}
 
 	err = yaml.Unmarshal(data, &config)
 	if err != nil {
-		return config, err
+		return config, nil, err
+	}
+	includes := config.Include
+
+	values := map[string]interface{}{}
+	for name, value := range config.TemplateParams {
+		values[name] = value
+	}
+	for name, value := range opts.Set {
+		values[name] = value
+	}
+	l := newLoader(values)
+
+	presets, err := l.loadUserPresets()
+	if err != nil {
+		return config, l, err
+	}
+
+	// If templates are specified, merge with template configuration
+	if parents := config.Parents(); len(parents) > 0 {
+		templateConfig, err := l.loadTemplates(parents)
+		if err != nil {
+			return config, l, err
+		}
+		config = MergeConfigs(MergeConfigs(presets, templateConfig), config)
+	} else {
+		// Merging applies the merge directives of the file itself
+		config = MergeConfigs(presets, config)
+	}
+	l.layers = append(l.layers, layer{kind: "project", file: path, raw: data, data: data})
+	if config, err = l.mergeIncludes(config, includes, path, nil); err != nil {
+		return config, l, err
 	}
 
-	// If template is specified, merge with template configuration
-	if config.Template != "" {
-		templateConfig, err := LoadTemplate(config.Template)
+	// The local override file is merged last, with the same rules as templates
+	if localPath := LocalConfigFile(path); localPath != "" {
+		localData, err := readConfig(localPath)
 		if err != nil {
-			return config, err
+			return config, l, err
 		}
-		config = MergeConfigs(templateConfig, config)
+		var local Config
+		if err := yaml.Unmarshal(localData, &local); err != nil {
+			return config, l, fmt.Errorf("%s: %v", localPath, err)
+		}
+		config = MergeConfigs(config, local)
+		l.layers = append(l.layers, layer{kind: "local", file: localPath, raw: localData, data: localData})
+		if config, err = l.mergeIncludes(config, local.Include, localPath, nil); err != nil {
+			return config, l, err
+		}
+	}
+
+	if config, err = ApplyProfiles(config, opts.Profiles); err != nil {
+		return config, l, err
+	}
+	if config, err = applyPresets(config); err != nil {
+		return config, l, err
+	}
+
+	if l.envFiles, err = envFilePaths(config, filepath.Dir(path)); err != nil {
+		return config, l, err
+	}
+	if err := Interpolate(&config, filepath.Dir(path)); err != nil {
+		return config, l, err
 	}
 
-	return config, nil
+	if config, err = expandForEach(config, filepath.Dir(path), opts.RunCommands); err != nil {
+		return config, l, err
+	}
+	config, err = ApplyConditions(config, filepath.Dir(path), opts.RunCommands)
+	return config, l, err
+}
+
+// JoinDirectory resolves the directory of a window or pane, child, against
+// the directory it inherits, parent. Absolute directories and those under
+// ~, which the shell expands, are kept as they are.
+func JoinDirectory(parent, child string) string {
+	if child == "" {
+		return parent
+	}
+	if filepath.IsAbs(child) || strings.HasPrefix(child, "~") || parent == "" {
+		return child
+	}
+	return filepath.Join(parent, child)
 }
 
-// GetConfigDir returns the path to the configuration directory
+// GetConfigDir returns the path to the configuration directory:
+// $TMUX_SETUP_HOME, $XDG_CONFIG_HOME/tmux-setup or ~/.config/tmux-setup
 func GetConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
-		return "", err
+	configDir := os.Getenv("TMUX_SETUP_HOME")
+	if configDir == "" {
+		base := os.Getenv("XDG_CONFIG_HOME")
+		if base == "" {
+			homeDir, err := os.UserHomeDir()
+			if err != nil {
+				return "", err
+			}
+			base = filepath.Join(homeDir, ".config")
+		}
+		configDir = filepath.Join(base, "tmux-setup")
 	}
 
-	configDir := filepath.Join(homeDir, ".config", "tmux-setup")
 	if err := os.MkdirAll(configDir, 0755); err != nil {
 		return "", err
 	}
@@ -0,0 +1,173 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"gopkg.in/yaml.v3"
+)
+
+// MergeFile merges cfg into the config file at path with MergeConfigs, like
+// a project file into its template, and returns the file's new contents.
+// The file's YAML is edited in place, so the comments, key order and
+// anchors of what the merge leaves unchanged survive.
+func MergeFile(path string, cfg Config) ([]byte, error) {
+	data, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	var existing Config
+	if err := doc.Decode(&existing); err != nil && len(doc.Content) > 0 {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+
+	merged := MergeConfigs(existing, cfg)
+	// The file's own windows keep their merge directives, which are meant
+	// for its templates
+	for i, window := range merged.Windows {
+		j := slices.IndexFunc(existing.Windows, func(w WindowConfig) bool { return w.Name == window.Name })
+		if window.Name != "" && j >= 0 {
+			merged.Windows[i].Patch = existing.Windows[j].Patch
+			merged.Windows[i].InsertAfter = existing.Windows[j].InsertAfter
+		}
+	}
+
+	target := &yaml.Node{}
+	if err := target.Encode(merged); err != nil {
+		return nil, err
+	}
+	pruneEmpty(target)
+
+	var root *yaml.Node
+	if len(doc.Content) == 0 {
+		root = target
+		doc = yaml.Node{Kind: yaml.DocumentNode, Content: []*yaml.Node{root}}
+	} else {
+		root = syncNode(doc.Content[0], target)
+		doc.Content[0] = root
+	}
+
+	format := Format(path)
+	if format == "yaml" {
+		clearMergeTags(&doc)
+		data, err = encode(&doc, format)
+	} else {
+		data, err = encode(resolveAliases(root), format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return data, nil
+}
+
+// syncNode returns node changed to hold the values of target. The parts of
+// node whose values don't change are kept as they are, with their comments
+// and anchors, mappings keep the order of their keys and new keys are
+// added at the end.
+func syncNode(node, target *yaml.Node) *yaml.Node {
+	if sameValue(node, target) {
+		return node
+	}
+	// A scalar written for a list of one item (see StringList), or for a
+	// string field, already holds the value
+	if node.Kind == yaml.ScalarNode && target.Kind == yaml.SequenceNode && len(target.Content) == 1 {
+		target = target.Content[0]
+	}
+	if node.Kind == yaml.ScalarNode && target.Kind == yaml.ScalarNode && node.Value == target.Value {
+		return node
+	}
+	if node.Kind != target.Kind || node.Kind == yaml.AliasNode {
+		target.HeadComment, target.LineComment, target.FootComment = node.HeadComment, node.LineComment, node.FootComment
+		return target
+	}
+
+	switch node.Kind {
+	case yaml.ScalarNode:
+		node.Value, node.Tag, node.Style = target.Value, target.Tag, 0
+	case yaml.MappingNode:
+		var content []*yaml.Node
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if value := mappingValue(target, node.Content[i].Value); value != nil {
+				content = append(content, node.Content[i], syncNode(node.Content[i+1], value))
+			}
+		}
+		for i := 0; i+1 < len(target.Content); i += 2 {
+			if mappingValue(node, target.Content[i].Value) == nil {
+				content = append(content, target.Content[i], target.Content[i+1])
+			}
+		}
+		node.Content = content
+	case yaml.SequenceNode:
+		// Items are matched by name, unnamed ones by position, and take
+		// the order of target
+		used := make([]bool, len(node.Content))
+		content := make([]*yaml.Node, len(target.Content))
+		for i, item := range target.Content {
+			match := -1
+			name := mappingValue(item, "name")
+			for j, candidate := range node.Content {
+				candidateName := mappingValue(candidate, "name")
+				switch {
+				case used[j]:
+					continue
+				case name != nil && candidateName != nil && name.Value == candidateName.Value:
+				case name == nil && candidateName == nil && i == j:
+				default:
+					continue
+				}
+				match = j
+				break
+			}
+			if match < 0 {
+				content[i] = item
+				continue
+			}
+			used[match] = true
+			content[i] = syncNode(node.Content[match], item)
+		}
+		node.Content = content
+	}
+	return node
+}
+
+// clearMergeTags lets the encoder write the << merge keys below node as
+// they were written, instead of tagging them !!merge
+func clearMergeTags(node *yaml.Node) {
+	if node.Kind == yaml.MappingNode {
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if key := node.Content[i]; key.Tag == "!!merge" {
+				key.Tag = ""
+			}
+		}
+	}
+	for _, child := range node.Content {
+		clearMergeTags(child)
+	}
+}
+
+// sameValue reports whether two nodes decode to the same value
+func sameValue(a, b *yaml.Node) bool {
+	var aValue, bValue interface{}
+	if a.Decode(&aValue) != nil || b.Decode(&bValue) != nil {
+		return false
+	}
+	return reflect.DeepEqual(aValue, bValue)
+}
+
+// mappingValue returns the value of key in a mapping node, or nil
+func mappingValue(node *yaml.Node, key string) *yaml.Node {
+	if node.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == key {
+			return node.Content[i+1]
+		}
+	}
+	return nil
+}
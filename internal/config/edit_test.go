@@ -0,0 +1,228 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeFile(t *testing.T) {
+	const existing = `# project session
+session_name: web
+template: rails
+defaults:
+  pre_command: make setup # before everything
+dependencies:
+  - nvim
+window_presets:
+  shell: &shell
+    initial_command: zsh
+windows:
+  - name: editor # first
+    initial_command: nvim .
+  - name: web
+    $patch: replace
+    panes:
+      - initial_command: rails s
+      - name: console
+        initial_command: rails c
+  - <<: *shell
+    name: shell
+`
+
+	tests := []struct {
+		name string
+		cfg  Config
+		want string
+	}{
+		{
+			name: "imported values win",
+			cfg: Config{
+				SessionName:  "imported",
+				Defaults:     GlobalDefaults{PreCommand: "docker compose up -d"},
+				Dependencies: []string{"nvim", "docker"},
+			},
+			want: `# project session
+session_name: imported
+template: rails
+defaults:
+  pre_command: docker compose up -d # before everything
+dependencies:
+  - nvim
+  - docker
+window_presets:
+  shell: &shell
+    initial_command: zsh
+windows:
+  - name: editor # first
+    initial_command: nvim .
+  - name: web
+    $patch: replace
+    panes:
+      - initial_command: rails s
+      - name: console
+        initial_command: rails c
+  - <<: *shell
+    name: shell
+`,
+		},
+		{
+			name: "nothing to change",
+			cfg:  Config{Defaults: GlobalDefaults{PreCommand: "make setup"}, Windows: []WindowConfig{{Name: "editor"}}},
+			want: existing,
+		},
+		{
+			name: "windows and panes are merged",
+			cfg: Config{Windows: []WindowConfig{
+				{Name: "web", Panes: []PaneConfig{
+					{InitialCommand: "bin/dev"},
+					{Name: "console", InitialCommand: "rails console"},
+				}},
+				{Name: "worker", Panes: []PaneConfig{{InitialCommand: "sidekiq"}}},
+			}},
+			want: `# project session
+session_name: web
+template: rails
+defaults:
+  pre_command: make setup # before everything
+dependencies:
+  - nvim
+window_presets:
+  shell: &shell
+    initial_command: zsh
+windows:
+  - name: editor # first
+    initial_command: nvim .
+  - name: web
+    $patch: replace
+    panes:
+      - initial_command: bin/dev
+      - name: console
+        initial_command: rails console
+  - <<: *shell
+    name: shell
+  - name: worker
+    panes:
+      - initial_command: sidekiq
+`,
+		},
+		{
+			name: "merge directives of the import",
+			cfg: Config{Windows: []WindowConfig{
+				{Name: "editor", Patch: PatchDelete},
+				{Name: "logs", InitialCommand: "tail -f log", InsertAfter: "web"},
+			}},
+			want: `# project session
+session_name: web
+template: rails
+defaults:
+  pre_command: make setup # before everything
+dependencies:
+  - nvim
+window_presets:
+  shell: &shell
+    initial_command: zsh
+windows:
+  - name: web
+    $patch: replace
+    panes:
+      - initial_command: rails s
+      - name: console
+        initial_command: rails c
+  - name: logs
+    initial_command: tail -f log
+  - <<: *shell
+    name: shell
+`,
+		},
+		{
+			name: "changed window with a merge key",
+			cfg:  Config{Windows: []WindowConfig{{Name: "shell", InitialCommand: "fish"}}},
+			want: `# project session
+session_name: web
+template: rails
+defaults:
+  pre_command: make setup # before everything
+dependencies:
+  - nvim
+window_presets:
+  shell: &shell
+    initial_command: zsh
+windows:
+  - name: editor # first
+    initial_command: nvim .
+  - name: web
+    $patch: replace
+    panes:
+      - initial_command: rails s
+      - name: console
+        initial_command: rails c
+  - name: shell
+    initial_command: fish
+`,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeFile(t, t.TempDir(), "tmux.conf.yml", existing)
+			data, err := MergeFile(path, test.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(data) != test.want {
+				t.Errorf("MergeFile() =\n%s\nwant\n%s", data, test.want)
+			}
+
+			// Merging the same import again changes nothing
+			path = writeFile(t, t.TempDir(), "tmux.conf.yml", string(data))
+			again, err := MergeFile(path, test.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(again) != string(data) {
+				t.Errorf("MergeFile() again =\n%s\nwant\n%s", again, data)
+			}
+		})
+	}
+}
+
+// TestMergeFileMatchesMergeConfigs checks that merging an import into a
+// file gives the configuration MergeConfigs gives
+func TestMergeFileMatchesMergeConfigs(t *testing.T) {
+	existing := Config{
+		SessionName: "web",
+		Defaults:    GlobalDefaults{Directory: "~/src/web", PreCommand: "make setup"},
+		Env:         map[string]string{"PORT": "3000"},
+		Windows: []WindowConfig{
+			{Name: "editor", InitialCommand: "nvim ."},
+			{Name: "server", Panes: []PaneConfig{{InitialCommand: "rails s"}, {InitialCommand: "htop"}}},
+		},
+	}
+	imported := Config{
+		Defaults: GlobalDefaults{PreCommand: "docker compose up -d"},
+		Env:      map[string]string{"DEBUG": "1"},
+		Windows: []WindowConfig{
+			{Name: "server", Panes: []PaneConfig{{Patch: PatchDelete}, {InitialCommand: "btop"}}},
+			{Name: "db", InitialCommand: "psql", InsertAfter: "editor"},
+		},
+	}
+	want := MergeConfigs(existing, imported)
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			data, err := Marshal(existing, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := writeFile(t, t.TempDir(), "tmux.conf"+FormatExt(format), string(data))
+			merged, err := MergeFile(path, imported)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := decodeFile(t, writeFile(t, t.TempDir(), "tmux.conf"+FormatExt(format), string(merged)))
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("MergeFile() =\n%+v\nwant\n%+v\n%s", got, want, merged)
+			}
+		})
+	}
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"text/template"
+)
+
+// ForEach stamps out a copy of a window or pane per item. Items from all
+// sources are used, in the order glob, items, command.
+type ForEach struct {
+	Glob    string   `yaml:"glob,omitempty" desc:"Glob of paths relative to the config file, e.g. services/*"`
+	Items   []string `yaml:"items,omitempty" desc:"List of items"`
+	Command string   `yaml:"command,omitempty" desc:"Shell command printing one item per line, run in the config file's directory"`
+}
+
+// forEachData is what the strings of a for_each element can refer to
+type forEachData struct {
+	Item  string // the path, list entry or output line
+	Name  string // the base name of Item
+	Index int    // the position of the item, from 0
+}
+
+// forEachFields are the fields of forEachData. Template rendering keeps
+// references to them for the for_each expansion.
+var forEachFields = []string{"Item", "Name", "Index"}
+
+var paneType = reflect.TypeOf(PaneConfig{})
+
+// expandForEach replaces the windows and panes that have for_each with a
+// copy per item. Command sources only run with runCommands; otherwise the
+// element is kept as it is.
+func expandForEach(cfg Config, baseDir string, runCommands bool) (Config, error) {
+	var windows []WindowConfig
+	for i, window := range cfg.Windows {
+		copies, err := expandElement(window, window.ForEach, baseDir, runCommands)
+		if err != nil {
+			return cfg, fmt.Errorf("windows[%d].for_each: %v", i, err)
+		}
+
+		for k := range copies {
+			var panes []PaneConfig
+			for j, pane := range copies[k].Panes {
+				paneCopies, err := expandElement(pane, pane.ForEach, baseDir, runCommands)
+				if err != nil {
+					return cfg, fmt.Errorf("windows[%d].panes[%d].for_each: %v", i, j, err)
+				}
+				panes = append(panes, paneCopies...)
+			}
+			copies[k].Panes = panes
+		}
+		windows = append(windows, copies...)
+	}
+	cfg.Windows = windows
+	return cfg, nil
+}
+
+// expandElement returns the copies of a window or pane for the items of f
+func expandElement[T WindowConfig | PaneConfig](element T, f *ForEach, baseDir string, runCommands bool) ([]T, error) {
+	if f == nil {
+		return []T{element}, nil
+	}
+	if f.Command != "" && !runCommands {
+		return []T{element}, nil
+	}
+
+	items, err := f.items(baseDir)
+	if err != nil {
+		return nil, err
+	}
+
+	clearForEach(&element)
+	copies := make([]T, 0, len(items))
+	for index, item := range items {
+		data := forEachData{Item: item, Name: filepath.Base(item), Index: index}
+		rendered, err := renderValue(reflect.ValueOf(element), data)
+		if err != nil {
+			return nil, fmt.Errorf("item %q: %v", item, err)
+		}
+		copies = append(copies, rendered.Interface().(T))
+	}
+	return copies, nil
+}
+
+func clearForEach(element interface{}) {
+	switch e := element.(type) {
+	case *WindowConfig:
+		e.ForEach = nil
+	case *PaneConfig:
+		e.ForEach = nil
+	}
+}
+
+// items lists the items of all sources
+func (f *ForEach) items(baseDir string) ([]string, error) {
+	var items []string
+
+	if f.Glob != "" {
+		matches, err := filepath.Glob(filepath.Join(baseDir, f.Glob))
+		if err != nil {
+			return nil, err
+		}
+		for _, match := range matches {
+			if rel, err := filepath.Rel(baseDir, match); err == nil {
+				match = rel
+			}
+			items = append(items, match)
+		}
+	}
+
+	items = append(items, f.Items...)
+
+	if f.Command != "" {
+		cmd := exec.Command("sh", "-c", f.Command)
+		cmd.Dir = baseDir
+		output, err := cmd.Output()
+		if err != nil {
+			return nil, fmt.Errorf("command %q failed: %v", f.Command, err)
+		}
+		for _, line := range strings.Split(string(output), "\n") {
+			if line = strings.TrimSpace(line); line != "" {
+				items = append(items, line)
+			}
+		}
+	}
+
+	return items, nil
+}
+
+// renderValue returns a deep copy of v with the strings executed as
+// templates. Panes with their own for_each are copied unchanged, so their
+// references are left for their own expansion.
+func renderValue(v reflect.Value, data forEachData) (reflect.Value, error) {
+	if v.Type() == paneType && v.Interface().(PaneConfig).ForEach != nil {
+		return v, nil
+	}
+
+	result := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.String:
+		rendered, err := renderString(v.String(), data)
+		if err != nil {
+			return result, err
+		}
+		result.SetString(rendered)
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return v, nil
+		}
+		elem, err := renderValue(v.Elem(), data)
+		if err != nil {
+			return result, err
+		}
+		if v.Kind() == reflect.Pointer {
+			ptr := reflect.New(v.Elem().Type())
+			ptr.Elem().Set(elem)
+			result.Set(ptr)
+		} else {
+			result.Set(elem)
+		}
+	case reflect.Slice:
+		if v.IsNil() {
+			return v, nil
+		}
+		result.Set(reflect.MakeSlice(v.Type(), v.Len(), v.Len()))
+		for i := 0; i < v.Len(); i++ {
+			elem, err := renderValue(v.Index(i), data)
+			if err != nil {
+				return result, err
+			}
+			result.Index(i).Set(elem)
+		}
+	case reflect.Map:
+		if v.IsNil() {
+			return v, nil
+		}
+		result.Set(reflect.MakeMapWithSize(v.Type(), v.Len()))
+		iter := v.MapRange()
+		for iter.Next() {
+			elem, err := renderValue(iter.Value(), data)
+			if err != nil {
+				return result, err
+			}
+			result.SetMapIndex(iter.Key(), elem)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !v.Type().Field(i).IsExported() {
+				continue
+			}
+			field, err := renderValue(v.Field(i), data)
+			if err != nil {
+				return result, err
+			}
+			result.Field(i).Set(field)
+		}
+	default:
+		result.Set(v)
+	}
+	return result, nil
+}
+
+func renderString(s string, data forEachData) (string, error) {
+	if !strings.Contains(s, "{{") {
+		return s, nil
+	}
+	tmpl, err := template.New("for_each").Option("missingkey=error").Parse(s)
+	if err != nil {
+		return "", err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, data); err != nil {
+		return "", err
+	}
+	return rendered.String(), nil
+}
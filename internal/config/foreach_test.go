@@ -0,0 +1,146 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandForEach(t *testing.T) {
+	dir := t.TempDir()
+	for _, service := range []string{"billing", "auth"} {
+		if err := os.MkdirAll(filepath.Join(dir, "services", service), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name        string
+		windows     []WindowConfig
+		runCommands bool
+		want        []WindowConfig
+		wantErr     bool
+	}{
+		{
+			name: "glob in name order, relative to the config file",
+			windows: []WindowConfig{{
+				Name:      "{{ .Name }}",
+				Directory: "{{ .Item }}",
+				ForEach:   &ForEach{Glob: "services/*"},
+				Panes:     []PaneConfig{{InitialCommand: "make -C {{ .Item }} run # {{ .Index }}"}},
+			}},
+			want: []WindowConfig{
+				{Name: "auth", Directory: "services/auth", Panes: []PaneConfig{{InitialCommand: "make -C services/auth run # 0"}}},
+				{Name: "billing", Directory: "services/billing", Panes: []PaneConfig{{InitialCommand: "make -C services/billing run # 1"}}},
+			},
+		},
+		{
+			name: "items and the elements around them",
+			windows: []WindowConfig{
+				{Name: "editor"},
+				{Name: "logs-{{ .Item }}", ForEach: &ForEach{Items: []string{"api", "web"}}, Env: map[string]string{"SERVICE": "{{ .Item }}"}},
+				{Name: "shell"},
+			},
+			want: []WindowConfig{
+				{Name: "editor"},
+				{Name: "logs-api", Env: map[string]string{"SERVICE": "api"}},
+				{Name: "logs-web", Env: map[string]string{"SERVICE": "web"}},
+				{Name: "shell"},
+			},
+		},
+		{
+			name:    "Name is the base name of a list item",
+			windows: []WindowConfig{{Name: "{{ .Name }}", ForEach: &ForEach{Items: []string{"apps/web"}}}},
+			want:    []WindowConfig{{Name: "web"}},
+		},
+		{
+			name:    "glob before items",
+			windows: []WindowConfig{{Name: "{{ .Index }}-{{ .Name }}", ForEach: &ForEach{Glob: "services/b*", Items: []string{"extra"}}}},
+			want:    []WindowConfig{{Name: "0-billing"}, {Name: "1-extra"}},
+		},
+		{
+			name:    "no items",
+			windows: []WindowConfig{{Name: "{{ .Name }}", ForEach: &ForEach{Glob: "missing/*"}}, {Name: "editor"}},
+			want:    []WindowConfig{{Name: "editor"}},
+		},
+		{
+			name: "panes",
+			windows: []WindowConfig{{Name: "tests", Panes: []PaneConfig{
+				{InitialCommand: "htop"},
+				{Name: "{{ .Name }}", InitialCommand: "go test ./{{ .Item }}/...", ForEach: &ForEach{Items: []string{"cmd", "internal"}}},
+			}}},
+			want: []WindowConfig{{Name: "tests", Panes: []PaneConfig{
+				{InitialCommand: "htop"},
+				{Name: "cmd", InitialCommand: "go test ./cmd/..."},
+				{Name: "internal", InitialCommand: "go test ./internal/..."},
+			}}},
+		},
+		{
+			name: "panes of copies use their own items",
+			windows: []WindowConfig{{
+				Name:    "{{ .Item }}",
+				ForEach: &ForEach{Items: []string{"a", "b"}},
+				Panes:   []PaneConfig{{InitialCommand: "echo {{ .Item }}", ForEach: &ForEach{Items: []string{"1", "2"}}}},
+			}},
+			want: []WindowConfig{
+				{Name: "a", Panes: []PaneConfig{{InitialCommand: "echo 1"}, {InitialCommand: "echo 2"}}},
+				{Name: "b", Panes: []PaneConfig{{InitialCommand: "echo 1"}, {InitialCommand: "echo 2"}}},
+			},
+		},
+		{
+			name:        "command",
+			windows:     []WindowConfig{{Name: "{{ .Item }}", ForEach: &ForEach{Command: "printf 'one\\n\\n  two \\n'"}}},
+			runCommands: true,
+			want:        []WindowConfig{{Name: "one"}, {Name: "two"}},
+		},
+		{
+			name:    "command isn't run without runCommands",
+			windows: []WindowConfig{{Name: "{{ .Item }}", ForEach: &ForEach{Command: "echo one"}}},
+			want:    []WindowConfig{{Name: "{{ .Item }}", ForEach: &ForEach{Command: "echo one"}}},
+		},
+		{
+			name:        "failing command",
+			windows:     []WindowConfig{{Name: "{{ .Item }}", ForEach: &ForEach{Command: "exit 1"}}},
+			runCommands: true,
+			wantErr:     true,
+		},
+		{
+			name:    "unknown field",
+			windows: []WindowConfig{{Name: "{{ .Service }}", ForEach: &ForEach{Items: []string{"api"}}}},
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := expandForEach(Config{Windows: test.windows}, dir, test.runCommands)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expandForEach() = %+v, want an error", got.Windows)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Windows, test.want) {
+				t.Errorf("expandForEach() =\n%+v\nwant\n%+v", got.Windows, test.want)
+			}
+		})
+	}
+}
+
+func TestExpandForEachKeepsTheOriginal(t *testing.T) {
+	window := WindowConfig{
+		Name:    "{{ .Item }}",
+		ForEach: &ForEach{Items: []string{"a"}},
+		Env:     map[string]string{"ITEM": "{{ .Item }}"},
+	}
+	if _, err := expandForEach(Config{Windows: []WindowConfig{window}}, t.TempDir(), false); err != nil {
+		t.Fatal(err)
+	}
+	if window.Env["ITEM"] != "{{ .Item }}" {
+		t.Errorf("the original window's env was changed to %q", window.Env["ITEM"])
+	}
+}
@@ -0,0 +1,494 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+	"gopkg.in/yaml.v3"
+)
+
+// ConfigFileNames are the names of project config files, in the order
+// FindConfigFile looks for them
+var ConfigFileNames = []string{"tmux.conf.yml", "tmux.conf.yaml", "tmux.conf.toml", "tmux.conf.json"}
+
+// Formats are the config file formats, as accepted by Convert
+var Formats = []string{"yaml", "toml", "json"}
+
+// Format returns the format of a config file from its extension. Files
+// without a known extension are YAML.
+func Format(path string) string {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".toml":
+		return "toml"
+	case ".json":
+		return "json"
+	}
+	return "yaml"
+}
+
+// FormatExt returns the file extension for a format
+func FormatExt(format string) string {
+	if format == "yaml" {
+		return ".yml"
+	}
+	return "." + format
+}
+
+// readConfig reads a config file as YAML. JSON is YAML already; TOML is
+// converted, keeping the order of its keys.
+func readConfig(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err != nil || Format(path) != "toml" {
+		return data, err
+	}
+	node, err := tomlNode(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return yaml.Marshal(node)
+}
+
+// tomlNode decodes a TOML document into a YAML node
+func tomlNode(data []byte) (*yaml.Node, error) {
+	var doc map[string]interface{}
+	meta, err := toml.Decode(string(data), &doc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Positions are keyed by the path to each key, with the index of the
+	// element for arrays of tables, which can each have other keys
+	order := map[string]int{}
+	elements := map[string]int{}
+	for i, key := range meta.Keys() {
+		var path []string
+		for j := range key {
+			path = append(path, key[j])
+			if _, ok := order[strings.Join(path, "\x00")]; !ok {
+				order[strings.Join(path, "\x00")] = i
+			}
+			if meta.Type(key[:j+1]...) == "ArrayHash" {
+				if j == len(key)-1 {
+					// the [[header]] of a new element
+					elements[strings.Join(path, "\x00")]++
+				}
+				path = append(path, strconv.Itoa(elements[strings.Join(path, "\x00")]-1))
+			}
+		}
+	}
+
+	node := &yaml.Node{}
+	if err := node.Encode(doc); err != nil {
+		return nil, err
+	}
+	sortKeys(node, nil, order)
+	return node, nil
+}
+
+// sortKeys puts the keys of the mappings below node in the order of
+// order. yaml.v3 encodes maps sorted by key.
+func sortKeys(node *yaml.Node, path []string, order map[string]int) {
+	switch node.Kind {
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			sortKeys(item, append(path, strconv.Itoa(i)), order)
+		}
+	case yaml.MappingNode:
+		type entry struct {
+			key, value *yaml.Node
+			position   int
+		}
+		entries := make([]entry, 0, len(node.Content)/2)
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			keyPath := append(append([]string{}, path...), node.Content[i].Value)
+			sortKeys(node.Content[i+1], keyPath, order)
+			entries = append(entries, entry{node.Content[i], node.Content[i+1], order[strings.Join(keyPath, "\x00")]})
+		}
+		sort.SliceStable(entries, func(i, j int) bool {
+			return entries[i].position < entries[j].position
+		})
+		node.Content = node.Content[:0]
+		for _, e := range entries {
+			node.Content = append(node.Content, e.key, e.value)
+		}
+	}
+}
+
+// Convert returns the config file at path in another format. Comments and
+// YAML anchors don't survive the conversion; the values are the same.
+func Convert(path, format string) ([]byte, error) {
+	data, err := readConfig(path)
+	if err != nil {
+		return nil, err
+	}
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, fmt.Errorf("%s is empty", path)
+	}
+	node := resolveAliases(doc.Content[0])
+	if format == "yaml" && Format(path) == "json" {
+		clearStyle(node)
+	}
+	data, err = encode(node, format)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return data, nil
+}
+
+// Marshal encodes cfg in a format, leaving out empty values
+func Marshal(cfg Config, format string) ([]byte, error) {
+	node := &yaml.Node{}
+	if err := node.Encode(cfg); err != nil {
+		return nil, err
+	}
+	pruneEmpty(node)
+	return encode(node, format)
+}
+
+// pruneEmpty removes the mapping entries below node whose values are null,
+// empty strings or empty collections
+func pruneEmpty(node *yaml.Node) {
+	for _, child := range node.Content {
+		pruneEmpty(child)
+	}
+	if node.Kind != yaml.MappingNode {
+		return
+	}
+	content := node.Content[:0]
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		value := node.Content[i+1]
+		empty := value.Tag == "!!null" ||
+			(value.Kind == yaml.ScalarNode && value.Tag == "!!str" && value.Value == "") ||
+			(value.Kind != yaml.ScalarNode && len(value.Content) == 0)
+		if !empty {
+			content = append(content, node.Content[i], value)
+		}
+	}
+	node.Content = content
+}
+
+// encode writes a document node in a format
+func encode(node *yaml.Node, format string) ([]byte, error) {
+	switch format {
+	case "yaml":
+		var out bytes.Buffer
+		encoder := yaml.NewEncoder(&out)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(node); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case "json":
+		value, err := jsonValue(node)
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		encoder := json.NewEncoder(&out)
+		encoder.SetEscapeHTML(false)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(value); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	case "toml":
+		if node.Kind != yaml.MappingNode {
+			return nil, fmt.Errorf("a TOML document must be a mapping")
+		}
+		var out bytes.Buffer
+		if err := writeTOMLTable(&out, nil, node); err != nil {
+			return nil, err
+		}
+		return out.Bytes(), nil
+	}
+	return nil, fmt.Errorf("unknown format %q (available: %s)", format, strings.Join(Formats, ", "))
+}
+
+// resolveAliases returns a copy of node with aliases replaced by what they
+// refer to and << merge keys expanded, for formats without them
+func resolveAliases(node *yaml.Node) *yaml.Node {
+	if node.Kind == yaml.AliasNode {
+		return resolveAliases(node.Alias)
+	}
+	result := *node
+	result.Anchor = ""
+	result.Content = nil
+
+	if node.Kind != yaml.MappingNode {
+		for _, child := range node.Content {
+			result.Content = append(result.Content, resolveAliases(child))
+		}
+		return &result
+	}
+
+	var merged []*yaml.Node
+	defined := map[string]bool{}
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i], resolveAliases(node.Content[i+1])
+		if key.Value != "<<" || key.Tag != "!!merge" {
+			result.Content = append(result.Content, key, value)
+			defined[key.Value] = true
+			continue
+		}
+		sources := []*yaml.Node{value}
+		if value.Kind == yaml.SequenceNode {
+			sources = value.Content
+		}
+		for _, source := range sources {
+			merged = append(merged, source.Content...)
+		}
+	}
+	for i := 0; i+1 < len(merged); i += 2 {
+		if !defined[merged[i].Value] {
+			result.Content = append(result.Content, merged[i], merged[i+1])
+			defined[merged[i].Value] = true
+		}
+	}
+	return &result
+}
+
+// clearStyle lets the encoder choose how to write node, instead of the
+// flow style and quotes of JSON
+func clearStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		clearStyle(child)
+	}
+}
+
+// orderedMap is a JSON object that keeps the order of its keys
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m orderedMap) MarshalJSON() ([]byte, error) {
+	var out bytes.Buffer
+	out.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			out.WriteByte(',')
+		}
+		name, err := marshalJSON(key)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshalJSON(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		out.Write(name)
+		out.WriteByte(':')
+		out.Write(value)
+	}
+	out.WriteByte('}')
+	return out.Bytes(), nil
+}
+
+func marshalJSON(v interface{}) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := json.NewEncoder(&out)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(out.Bytes(), []byte("\n")), nil
+}
+
+// jsonValue converts a YAML node to values encoding/json writes in order
+func jsonValue(node *yaml.Node) (interface{}, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		m := orderedMap{values: map[string]interface{}{}}
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			value, err := jsonValue(node.Content[i+1])
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := m.values[key]; !ok {
+				m.keys = append(m.keys, key)
+			}
+			m.values[key] = value
+		}
+		return m, nil
+	case yaml.SequenceNode:
+		list := make([]interface{}, 0, len(node.Content))
+		for _, item := range node.Content {
+			value, err := jsonValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return nil, err
+	}
+	if f, ok := value.(float64); ok && (math.IsInf(f, 0) || math.IsNaN(f)) {
+		return nil, fmt.Errorf("line %d: %v can't be written as JSON", node.Line, f)
+	}
+	return value, nil
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// writeTOMLTable writes the entries of a mapping node as the TOML table at
+// path. Plain values come first, as TOML requires, then sub-tables and
+// arrays of tables. Null values are left out, TOML has no null.
+func writeTOMLTable(out *bytes.Buffer, path []string, node *yaml.Node) error {
+	var tables []int
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key, value := node.Content[i].Value, node.Content[i+1]
+		if value.Tag == "!!null" {
+			continue
+		}
+		if isTOMLTable(value) {
+			tables = append(tables, i)
+			continue
+		}
+		formatted, err := tomlValue(value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", tomlKey(append(path, key)), err)
+		}
+		fmt.Fprintf(out, "%s = %s\n", tomlKey([]string{key}), formatted)
+	}
+
+	for _, i := range tables {
+		tablePath := append(append([]string{}, path...), node.Content[i].Value)
+		value := node.Content[i+1]
+		if value.Kind == yaml.MappingNode {
+			fmt.Fprintf(out, "\n[%s]\n", tomlKey(tablePath))
+			if err := writeTOMLTable(out, tablePath, value); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, item := range value.Content {
+			fmt.Fprintf(out, "\n[[%s]]\n", tomlKey(tablePath))
+			if err := writeTOMLTable(out, tablePath, item); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// isTOMLTable reports whether a value is written as a [table] or an array
+// of [[tables]] rather than inline
+func isTOMLTable(node *yaml.Node) bool {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return len(node.Content) > 0
+	case yaml.SequenceNode:
+		for _, item := range node.Content {
+			if item.Kind != yaml.MappingNode {
+				return false
+			}
+		}
+		return len(node.Content) > 0
+	}
+	return false
+}
+
+func tomlKey(path []string) string {
+	parts := make([]string, len(path))
+	for i, key := range path {
+		if bareKey.MatchString(key) {
+			parts[i] = key
+		} else {
+			parts[i] = tomlString(key)
+		}
+	}
+	return strings.Join(parts, ".")
+}
+
+// tomlString quotes s as a TOML basic string, whose escapes are JSON's
+func tomlString(s string) string {
+	data, _ := marshalJSON(s)
+	return string(data)
+}
+
+// tomlValue formats a value written inline
+func tomlValue(node *yaml.Node) (string, error) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		var entries []string
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i+1].Tag == "!!null" {
+				continue
+			}
+			value, err := tomlValue(node.Content[i+1])
+			if err != nil {
+				return "", err
+			}
+			entries = append(entries, tomlKey([]string{node.Content[i].Value})+" = "+value)
+		}
+		if len(entries) == 0 {
+			return "{}", nil
+		}
+		return "{ " + strings.Join(entries, ", ") + " }", nil
+	case yaml.SequenceNode:
+		items := make([]string, 0, len(node.Content))
+		for _, item := range node.Content {
+			if item.Tag == "!!null" {
+				return "", fmt.Errorf("line %d: TOML arrays can't contain null", item.Line)
+			}
+			value, err := tomlValue(item)
+			if err != nil {
+				return "", err
+			}
+			items = append(items, value)
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	}
+
+	var value interface{}
+	if err := node.Decode(&value); err != nil {
+		return "", err
+	}
+	switch v := value.(type) {
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case uint64:
+		return strconv.FormatUint(v, 10), nil
+	case float64:
+		switch {
+		case math.IsInf(v, 1):
+			return "inf", nil
+		case math.IsInf(v, -1):
+			return "-inf", nil
+		case math.IsNaN(v):
+			return "nan", nil
+		}
+		formatted := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(formatted, ".eEn") {
+			formatted += ".0"
+		}
+		return formatted, nil
+	}
+	// timestamps and other scalars are kept as strings
+	return tomlString(node.Value), nil
+}
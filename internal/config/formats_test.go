@@ -0,0 +1,253 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+const roundTripConfig = `session_name: web
+focus_window: 2
+defaults:
+  directory: ~/src/web
+  initial_command: clear
+  pre_command: docker compose up -d
+dependencies:
+  - nvim
+  - docker
+env:
+  PORT: "8080"
+  DEBUG: "true"
+env_file:
+  - .env
+params:
+  - name: port
+    type: int
+    default: 3000
+  - name: watch
+    type: bool
+    default: true
+template_params:
+  port: 8080
+  ratio: 0.5
+windows:
+  - name: editor
+    initial_command: nvim .
+  - name: server
+    directory: api
+    layout:
+      direction: horizontal
+      panes:
+        - width: 70%
+        - width: 30%
+    panes:
+      - name: run
+        initial_command: go run .
+        refresh_interval: 5
+        when:
+          env_equals:
+            APP_ENV: dev
+      - initial_command: tail -f "log/dev.log"
+        env:
+          LEVEL: debug
+  - name: logs
+    layout: tiled
+    $patch: replace
+    insert_after: editor
+    for_each:
+      items:
+        - api
+        - worker
+`
+
+// writeFile writes data to a file of dir and returns its path
+func writeFile(t *testing.T, dir, name, data string) string {
+	t.Helper()
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// decodeFile reads a config file of any format
+func decodeFile(t *testing.T, path string) Config {
+	t.Helper()
+	data, err := readConfig(path)
+	if err != nil {
+		t.Fatalf("readConfig(%s): %v", filepath.Base(path), err)
+	}
+	var cfg Config
+	if err := yaml.Unmarshal(data, &cfg); err != nil {
+		t.Fatalf("decoding %s: %v\n%s", filepath.Base(path), err, data)
+	}
+	return cfg
+}
+
+func TestConvertRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	source := writeFile(t, dir, "source.yml", roundTripConfig)
+	want := decodeFile(t, source)
+
+	for _, from := range Formats {
+		for _, to := range Formats {
+			t.Run(from+" to "+to, func(t *testing.T) {
+				// from is reached from the YAML source first
+				data, err := Convert(source, from)
+				if err != nil {
+					t.Fatalf("Convert(yaml, %s): %v", from, err)
+				}
+				path := writeFile(t, t.TempDir(), "tmux.conf"+FormatExt(from), string(data))
+
+				if data, err = Convert(path, to); err != nil {
+					t.Fatalf("Convert(%s, %s): %v", from, to, err)
+				}
+				path = writeFile(t, t.TempDir(), "tmux.conf"+FormatExt(to), string(data))
+
+				if got := decodeFile(t, path); !reflect.DeepEqual(got, want) {
+					t.Errorf("%s to %s changed the config:\n%s\ngot  %+v\nwant %+v", from, to, data, got, want)
+				}
+			})
+		}
+	}
+}
+
+func TestConvertKeepsKeyOrder(t *testing.T) {
+	source := writeFile(t, t.TempDir(), "source.yml", roundTripConfig)
+	want, err := Convert(source, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := Convert(source, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := writeFile(t, t.TempDir(), "tmux.conf.json", string(data))
+	got, err := Convert(path, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("yaml to json to yaml =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestReadConfigKeepsTOMLKeyOrder(t *testing.T) {
+	// TOML puts plain values before tables, so only their order within
+	// each table can be kept
+	path := writeFile(t, t.TempDir(), "tmux.conf.toml", `session_name = "web"
+focus_window = 2
+
+[defaults]
+initial_command = "clear"
+directory = "~/src/web"
+
+[[windows]]
+name = "editor"
+initial_command = "nvim ."
+
+[[windows]]
+name = "server"
+layout = "tiled"
+
+[[windows.panes]]
+name = "run"
+initial_command = "go run ."
+
+[[windows.panes]]
+initial_command = "htop"
+name = "top"
+
+[windows.env]
+PORT = "8080"
+DEBUG = "true"
+`)
+	want := `session_name: web
+focus_window: 2
+defaults:
+  initial_command: clear
+  directory: ~/src/web
+windows:
+  - name: editor
+    initial_command: nvim .
+  - name: server
+    layout: tiled
+    panes:
+      - name: run
+        initial_command: go run .
+      - initial_command: htop
+        name: top
+    env:
+      PORT: "8080"
+      DEBUG: "true"
+`
+	got, err := Convert(path, "yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(got) != want {
+		t.Errorf("Convert(toml, yaml) =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestConvertResolvesAliases(t *testing.T) {
+	source := writeFile(t, t.TempDir(), "source.yml", `session_name: web
+window_presets:
+  shell: &shell
+    initial_command: zsh
+windows:
+  - <<: *shell
+    name: one
+  - <<: *shell
+    name: two
+    initial_command: fish
+`)
+	data, err := Convert(source, "json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := decodeFile(t, writeFile(t, t.TempDir(), "tmux.conf.json", string(data)))
+
+	want := []WindowConfig{
+		{Name: "one", InitialCommand: "zsh"},
+		{Name: "two", InitialCommand: "fish"},
+	}
+	if !reflect.DeepEqual(got.Windows, want) {
+		t.Errorf("windows = %+v, want %+v", got.Windows, want)
+	}
+}
+
+func TestMarshalRoundTrip(t *testing.T) {
+	focus := 2
+	want := Config{
+		SessionName:  "web",
+		FocusWindow:  &focus,
+		Defaults:     GlobalDefaults{Directory: "~/src/web"},
+		Dependencies: []string{"nvim"},
+		Env:          map[string]string{"PORT": "8080"},
+		Windows: []WindowConfig{
+			{Name: "editor", InitialCommand: "nvim ."},
+			{Name: "server", Layout: "main-vertical", Panes: []PaneConfig{
+				{Name: "run", InitialCommand: `echo "a \"quoted\" word"`, RefreshInterval: 5},
+				{InitialCommand: "htop", When: &Condition{EnvSet: "DISPLAY"}},
+			}},
+		},
+	}
+
+	for _, format := range Formats {
+		t.Run(format, func(t *testing.T) {
+			data, err := Marshal(want, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			path := writeFile(t, t.TempDir(), "tmux.conf"+FormatExt(format), string(data))
+			if got := decodeFile(t, path); !reflect.DeepEqual(got, want) {
+				t.Errorf("Marshal(%s) round trip =\n%+v\nwant\n%+v\n%s", format, got, want, data)
+			}
+		})
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// resolveIncludes expands include patterns, relative to dir, to the files
+// they name, in order. Patterns without glob characters must exist.
+func resolveIncludes(patterns []string, dir string) ([]string, error) {
+	var files []string
+	for _, pattern := range patterns {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(dir, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("include %s: %v", pattern, err)
+		}
+		if len(matches) == 0 && !strings.ContainsAny(pattern, "*?[") {
+			return nil, fmt.Errorf("include %s: no such file", pattern)
+		}
+		for _, match := range matches {
+			if !slices.Contains(files, match) {
+				files = append(files, match)
+			}
+		}
+	}
+	return files, nil
+}
+
+// mergeIncludes merges the fragments the file at path includes on top of
+// config, in order. Fragments can include further fragments. chain holds
+// the files being included, to detect cycles.
+func (l *loader) mergeIncludes(config Config, patterns []string, path string, chain []string) (Config, error) {
+	files, err := resolveIncludes(patterns, filepath.Dir(path))
+	if err != nil {
+		return config, fmt.Errorf("%s: %v", path, err)
+	}
+	if absPath, err := filepath.Abs(path); err == nil {
+		path = absPath
+	}
+	chain = append(chain, path)
+
+	for _, file := range files {
+		if absFile, err := filepath.Abs(file); err == nil {
+			file = absFile
+		}
+		if slices.Contains(chain, file) {
+			return config, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), file)
+		}
+
+		data, err := readConfig(file)
+		if err != nil {
+			return config, err
+		}
+		var fragment Config
+		if err := yaml.Unmarshal(data, &fragment); err != nil {
+			return config, fmt.Errorf("%s: %v", file, err)
+		}
+		l.layers = append(l.layers, layer{kind: "include", file: file, raw: data, data: data})
+
+		includes := fragment.Include
+		fragment.Include = nil
+		if fragment, err = l.mergeIncludes(fragment, includes, file, chain); err != nil {
+			return config, err
+		}
+		config = MergeConfigs(config, fragment)
+	}
+	return config, nil
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestIncludes(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are written to a temporary directory, tmux.conf.yml is
+		// loaded
+		files   map[string]string
+		want    []string // window names
+		wantErr string   // with the directory written as DIR
+	}{
+		{
+			name: "in order, after the file",
+			files: map[string]string{
+				"tmux.conf.yml": "include: [b.yml, a.yml]\nwindows:\n  - name: main\n",
+				"a.yml":         "windows:\n  - name: a\n",
+				"b.yml":         "windows:\n  - name: b\n",
+			},
+			want: []string{"main", "b", "a"},
+		},
+		{
+			name: "glob",
+			files: map[string]string{
+				"tmux.conf.yml":      "include: 'windows/*.yml'\n",
+				"windows/api.yml":    "windows:\n  - name: api\n",
+				"windows/web.yml":    "windows:\n  - name: web\n",
+				"windows/readme.txt": "not a fragment",
+			},
+			want: []string{"api", "web"},
+		},
+		{
+			name: "glob matching nothing",
+			files: map[string]string{
+				"tmux.conf.yml": "include: 'windows/*.yml'\nwindows:\n  - name: main\n",
+			},
+			want: []string{"main"},
+		},
+		{
+			name: "nested, relative to the including file",
+			files: map[string]string{
+				"tmux.conf.yml":   "include: [fragments/a.yml]\n",
+				"fragments/a.yml": "include: [b.yml]\nwindows:\n  - name: a\n",
+				"fragments/b.yml": "windows:\n  - name: b\n",
+				"b.yml":           "windows:\n  - name: wrong b\n",
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "the same fragment twice is no cycle",
+			files: map[string]string{
+				"tmux.conf.yml": "include: [a.yml, b.yml]\n",
+				"a.yml":         "include: [shared.yml]\nwindows:\n  - name: a\n",
+				"b.yml":         "include: [shared.yml]\nwindows:\n  - name: b\n",
+				"shared.yml":    "env:\n  SHARED: \"1\"\n",
+			},
+			want: []string{"a", "b"},
+		},
+		{
+			name: "a file matched twice is included once",
+			files: map[string]string{
+				"tmux.conf.yml": "include: [a.yml, 'a*.yml']\n",
+				"a.yml":         "windows:\n  - name: a\n",
+			},
+			want: []string{"a"},
+		},
+		{
+			name: "glob matching the including file",
+			files: map[string]string{
+				"tmux.conf.yml": "include: ['*.yml']\n",
+				"a.yml":         "windows:\n  - name: a\n",
+			},
+			wantErr: "include cycle: DIR/tmux.conf.yml -> DIR/tmux.conf.yml",
+		},
+		{
+			name: "missing file",
+			files: map[string]string{
+				"tmux.conf.yml": "include: [missing.yml]\n",
+			},
+			wantErr: "DIR/tmux.conf.yml: include DIR/missing.yml: no such file",
+		},
+		{
+			name: "file including itself",
+			files: map[string]string{
+				"tmux.conf.yml": "include: [tmux.conf.yml]\n",
+			},
+			wantErr: "include cycle: DIR/tmux.conf.yml -> DIR/tmux.conf.yml",
+		},
+		{
+			name: "cycle between fragments",
+			files: map[string]string{
+				"tmux.conf.yml": "include: [a.yml]\n",
+				"a.yml":         "include: [sub/b.yml]\n",
+				"sub/b.yml":     "include: [../a.yml]\n",
+			},
+			wantErr: "include cycle: DIR/tmux.conf.yml -> DIR/a.yml -> DIR/sub/b.yml -> DIR/a.yml",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("TMUX_SETUP_HOME", t.TempDir())
+			dir := t.TempDir()
+			for name, data := range test.files {
+				if err := os.MkdirAll(filepath.Join(dir, filepath.Dir(name)), 0755); err != nil {
+					t.Fatal(err)
+				}
+				writeFile(t, dir, name, data)
+			}
+
+			cfg, err := Load(filepath.Join(dir, "tmux.conf.yml"))
+			if test.wantErr != "" {
+				if err == nil {
+					t.Fatalf("Load() = %+v, want an error", cfg.Windows)
+				}
+				if got := strings.ReplaceAll(err.Error(), dir, "DIR"); got != test.wantErr {
+					t.Errorf("Load() error = %q, want %q", got, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			var names []string
+			for _, window := range cfg.Windows {
+				names = append(names, window.Name)
+			}
+			if !reflect.DeepEqual(names, test.want) {
+				t.Errorf("windows = %q, want %q", names, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,241 @@
+package config
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Interpolate expands ${VAR}, ${VAR:-default} and ${VAR:?error} in every
+// string field of cfg. Variables come from the environment, then from the
+// env_file entries, which are resolved relative to baseDir. "$${" is kept as
+// a literal "${".
+func Interpolate(cfg *Config, baseDir string) error {
+	envFiles, err := envFilePaths(*cfg, baseDir)
+	if err != nil {
+		return err
+	}
+	fileEnv := map[string]string{}
+	for i, envFile := range envFiles {
+		if err := loadDotenv(envFile, fileEnv); err != nil {
+			return fmt.Errorf("env_file[%d]: %v", i, err)
+		}
+	}
+
+	lookup := func(name string) (string, bool) {
+		if value, ok := os.LookupEnv(name); ok {
+			return value, true
+		}
+		value, ok := fileEnv[name]
+		return value, ok
+	}
+
+	return interpolateValue(reflect.ValueOf(cfg).Elem(), "", lookup)
+}
+
+// envFilePaths returns the paths of the env files of cfg, with variables
+// from the environment expanded and resolved relative to baseDir
+func envFilePaths(cfg Config, baseDir string) ([]string, error) {
+	paths := make([]string, len(cfg.EnvFile))
+	for i, envFile := range cfg.EnvFile {
+		envFile, err := expand(envFile, os.LookupEnv)
+		if err != nil {
+			return nil, fmt.Errorf("env_file[%d]: %v", i, err)
+		}
+		if !filepath.IsAbs(envFile) {
+			envFile = filepath.Join(baseDir, envFile)
+		}
+		paths[i] = envFile
+	}
+	return paths, nil
+}
+
+// interpolateValue expands the strings in v; path names v in error messages
+func interpolateValue(v reflect.Value, path string, lookup func(string) (string, bool)) error {
+	switch v.Kind() {
+	case reflect.String:
+		expanded, err := expand(v.String(), lookup)
+		if err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		v.SetString(expanded)
+	case reflect.Pointer:
+		if !v.IsNil() {
+			return interpolateValue(v.Elem(), path, lookup)
+		}
+	case reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		// Interface values aren't addressable, so expand a copy
+		value := reflect.New(v.Elem().Type()).Elem()
+		value.Set(v.Elem())
+		if err := interpolateValue(value, path, lookup); err != nil {
+			return err
+		}
+		v.Set(value)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := interpolateValue(v.Index(i), fmt.Sprintf("%s[%d]", path, i), lookup); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		for _, key := range v.MapKeys() {
+			value := reflect.New(v.Type().Elem()).Elem()
+			value.Set(v.MapIndex(key))
+			if err := interpolateValue(value, joinPath(path, fmt.Sprint(key.Interface())), lookup); err != nil {
+				return err
+			}
+			v.SetMapIndex(key, value)
+		}
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			field := v.Type().Field(i)
+			name := yamlName(field)
+			if name == "" || field.Tag.Get("interpolate") == "false" {
+				continue
+			}
+			if err := interpolateValue(v.Field(i), joinPath(path, name), lookup); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// expand replaces the ${...} references in s
+func expand(s string, lookup func(string) (string, bool)) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var result strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			result.WriteString(s)
+			return result.String(), nil
+		}
+
+		// "$${" escapes a literal "${"
+		if start > 0 && s[start-1] == '$' {
+			result.WriteString(s[:start-1])
+			result.WriteString("${")
+			s = s[start+2:]
+			continue
+		}
+
+		end := strings.Index(s[start:], "}")
+		if end < 0 {
+			return "", fmt.Errorf("unterminated variable reference in %q", s)
+		}
+		result.WriteString(s[:start])
+
+		value, err := expandReference(s[start+2:start+end], lookup)
+		if err != nil {
+			return "", err
+		}
+		result.WriteString(value)
+		s = s[start+end+1:]
+	}
+}
+
+// expandReference resolves the body of a ${...} reference
+func expandReference(reference string, lookup func(string) (string, bool)) (string, error) {
+	name, operator, argument := reference, "", ""
+	if i := strings.Index(reference, ":"); i >= 0 {
+		name, operator, argument = reference[:i], reference[i:min(i+2, len(reference))], reference[min(i+2, len(reference)):]
+	}
+	if !isVariableName(name) {
+		return "", fmt.Errorf("invalid variable name %q", name)
+	}
+
+	value, _ := lookup(name)
+	switch operator {
+	case "":
+		return value, nil
+	case ":-":
+		if value == "" {
+			return argument, nil
+		}
+		return value, nil
+	case ":?":
+		if value == "" {
+			if argument == "" {
+				argument = "is required"
+			}
+			return "", fmt.Errorf("%s %s", name, argument)
+		}
+		return value, nil
+	}
+	return "", fmt.Errorf("unsupported operator %q in ${%s}", operator, reference)
+}
+
+func isVariableName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r != '_' && (r < 'A' || r > 'Z') && (r < 'a' || r > 'z') && (i == 0 || r < '0' || r > '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// loadDotenv reads KEY=VALUE lines from a dotenv file into env. Later files
+// override earlier ones.
+func loadDotenv(path string, env map[string]string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || !isVariableName(key) {
+			return fmt.Errorf("%s:%d: expected KEY=VALUE", path, lineNumber)
+		}
+		env[key] = parseDotenvValue(strings.TrimSpace(value))
+	}
+	return scanner.Err()
+}
+
+func parseDotenvValue(value string) string {
+	if len(value) > 0 && (value[0] == '\'' || value[0] == '"') {
+		// Quoted values end at the closing quote, anything after it is ignored
+		if end := strings.LastIndexByte(value, value[0]); end > 0 {
+			quoted := value[1:end]
+			if value[0] == '"' {
+				replacer := strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\"`, `"`, `\\`, `\`)
+				quoted = replacer.Replace(quoted)
+			}
+			return quoted
+		}
+	}
+	// Unquoted values end at an inline comment
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value
+}
@@ -0,0 +1,84 @@
+package config
+
+import "testing"
+
+func TestExpand(t *testing.T) {
+	env := map[string]string{"HOME": "/home/me", "PORT": "8080", "EMPTY": ""}
+	lookup := func(name string) (string, bool) {
+		value, ok := env[name]
+		return value, ok
+	}
+
+	tests := []struct {
+		input   string
+		want    string
+		wantErr bool
+	}{
+		{input: "no references", want: "no references"},
+		{input: "${HOME}/src", want: "/home/me/src"},
+		{input: "${HOME}:${PORT}", want: "/home/me:8080"},
+		{input: "${MISSING}", want: ""},
+		{input: "${PORT:-3000}", want: "8080"},
+		{input: "${EMPTY:-3000}", want: "3000"},
+		{input: "${MISSING:-3000}", want: "3000"},
+		{input: "${MISSING:-}", want: ""},
+		{input: "${PORT:?must be set}", want: "8080"},
+		{input: "${MISSING:?must be set}", wantErr: true},
+		{input: "${EMPTY:?}", wantErr: true},
+		{input: "$${HOME}", want: "${HOME}"},
+		{input: "cost $5", want: "cost $5"},
+		{input: "$HOME", want: "$HOME"},
+		{input: "${HOME", wantErr: true},
+		{input: "${1PORT}", wantErr: true},
+		{input: "${}", wantErr: true},
+		{input: "${PORT:+set}", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			got, err := expand(test.input, lookup)
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("expand(%q) = %q, want an error", test.input, got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("expand(%q): %v", test.input, err)
+			}
+			if got != test.want {
+				t.Errorf("expand(%q) = %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+}
+
+func TestParseDotenvValue(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{input: "", want: ""},
+		{input: "plain", want: "plain"},
+		{input: "with spaces", want: "with spaces"},
+		{input: "value # comment", want: "value"},
+		{input: "value#not-a-comment", want: "value#not-a-comment"},
+		{input: `'single quoted'`, want: "single quoted"},
+		{input: `'no \n escapes'`, want: `no \n escapes`},
+		{input: `"double quoted"`, want: "double quoted"},
+		{input: `"line\nbreak"`, want: "line\nbreak"},
+		{input: `"tab\tand \"quotes\""`, want: "tab\tand \"quotes\""},
+		{input: `"back\\slash"`, want: `back\slash`},
+		{input: `"# not a comment"`, want: "# not a comment"},
+		{input: `"quoted" # comment`, want: "quoted"},
+		{input: `"unterminated`, want: `"unterminated`},
+	}
+
+	for _, test := range tests {
+		t.Run(test.input, func(t *testing.T) {
+			if got := parseDotenvValue(test.input); got != test.want {
+				t.Errorf("parseDotenvValue(%q) = %q, want %q", test.input, got, test.want)
+			}
+		})
+	}
+}
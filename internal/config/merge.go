@@ -0,0 +1,229 @@
+package config
+
+import (
+	"reflect"
+	"slices"
+)
+
+// Merge directives, set with "$patch" on a window or pane
+const (
+	// PatchMerge merges the element into the matching one (the default)
+	PatchMerge = "merge"
+	// PatchReplace replaces the matching element instead of merging into it.
+	// Given on an element without a name, it replaces the whole list.
+	PatchReplace = "replace"
+	// PatchDelete removes the matching element
+	PatchDelete = "delete"
+	// PatchAppend adds the element even if one with the same name exists
+	PatchAppend = "append"
+)
+
+var (
+	windowsType = reflect.TypeOf([]WindowConfig(nil))
+	panesType   = reflect.TypeOf([]PaneConfig(nil))
+)
+
+// MergeConfigs merges template config with user config, preferring user config values.
+//
+// Scalar fields are taken from user when they are set. Maps are merged key by
+// key, and entries that are structs (profiles) are merged themselves.
+// Dependencies and env files are concatenated without duplicates. Windows
+// are matched by name and panes by name (or by position when unnamed), and
+// matching elements are merged field by field. Windows and
+// panes can change this with "$patch" directives, and windows can be moved
+// with "insert_after".
+func MergeConfigs(template, user Config) Config {
+	return mergeValue(reflect.ValueOf(template), reflect.ValueOf(user)).Interface().(Config)
+}
+
+func mergeValue(base, overlay reflect.Value) reflect.Value {
+	switch base.Type() {
+	case windowsType:
+		return reflect.ValueOf(mergeWindows(base.Interface().([]WindowConfig), overlay.Interface().([]WindowConfig)))
+	case panesType:
+		return reflect.ValueOf(mergePanes(base.Interface().([]PaneConfig), overlay.Interface().([]PaneConfig)))
+	}
+
+	switch base.Kind() {
+	case reflect.Struct:
+		result := reflect.New(base.Type()).Elem()
+		for i := 0; i < base.NumField(); i++ {
+			if base.Type().Field(i).IsExported() {
+				result.Field(i).Set(mergeValue(base.Field(i), overlay.Field(i)))
+			}
+		}
+		return result
+	case reflect.Map:
+		if overlay.Len() == 0 {
+			return base
+		}
+		result := reflect.MakeMapWithSize(base.Type(), base.Len()+overlay.Len())
+		for _, m := range []reflect.Value{base, overlay} {
+			iter := m.MapRange()
+			for iter.Next() {
+				value := iter.Value()
+				// Entries like profiles are merged, plain values replaced
+				if existing := result.MapIndex(iter.Key()); existing.IsValid() && value.Kind() == reflect.Struct {
+					value = mergeValue(existing, value)
+				}
+				result.SetMapIndex(iter.Key(), value)
+			}
+		}
+		return result
+	case reflect.Slice:
+		if base.Type() == reflect.TypeOf([]string(nil)) {
+			return reflect.ValueOf(mergeStrings(base.Interface().([]string), overlay.Interface().([]string)))
+		}
+		if overlay.Len() > 0 {
+			return overlay
+		}
+		return base
+	}
+
+	// Scalars, pointers and interfaces are replaced when set
+	if overlay.IsZero() {
+		return base
+	}
+	return overlay
+}
+
+// mergeStrings concatenates two string lists, dropping duplicates
+func mergeStrings(base, overlay []string) []string {
+	var result []string
+	for _, value := range append(slices.Clone(base), overlay...) {
+		if !slices.Contains(result, value) {
+			result = append(result, value)
+		}
+	}
+	return result
+}
+
+// mergeWindows merges overlay into base. Overlay windows only match windows
+// of base, never each other: two windows of the same name in one file are
+// both kept.
+func mergeWindows(base, overlay []WindowConfig) []WindowConfig {
+	result := slices.Clone(base)
+	// fromBase tells which windows of result came from base and are still
+	// unmatched
+	fromBase := make([]bool, len(result))
+	for i := range fromBase {
+		fromBase[i] = true
+	}
+
+	for _, window := range overlay {
+		if window.Name == "" && window.Patch == PatchReplace {
+			// The window replaces the whole list, and is its first window
+			// unless it's only the directive
+			result, fromBase = nil, nil
+			if window.Patch = ""; reflect.ValueOf(window).IsZero() {
+				continue
+			}
+		}
+
+		index := -1
+		if window.Name != "" && window.Patch != PatchAppend {
+			index = slices.IndexFunc(result, func(w WindowConfig) bool { return w.Name == window.Name })
+			if index >= 0 && !fromBase[index] {
+				index = -1
+			}
+		}
+
+		if window.Patch == PatchDelete {
+			if index >= 0 {
+				result = slices.Delete(result, index, index+1)
+				fromBase = slices.Delete(fromBase, index, index+1)
+			}
+			continue
+		}
+
+		// New and replacing windows are merged onto nothing, which applies
+		// the directives of their panes
+		var merged WindowConfig
+		if index >= 0 && window.Patch != PatchReplace {
+			merged = mergeValue(reflect.ValueOf(result[index]), reflect.ValueOf(window)).Interface().(WindowConfig)
+		} else {
+			merged = mergeValue(reflect.ValueOf(WindowConfig{}), reflect.ValueOf(window)).Interface().(WindowConfig)
+		}
+		merged.Patch, merged.InsertAfter = "", ""
+
+		// A matched window is now the overlay's, so later windows of the
+		// same name don't match it again
+		if index >= 0 && window.InsertAfter == "" {
+			result[index], fromBase[index] = merged, false
+			continue
+		}
+		if index >= 0 {
+			result = slices.Delete(result, index, index+1)
+			fromBase = slices.Delete(fromBase, index, index+1)
+		}
+
+		position := len(result)
+		if window.InsertAfter != "" {
+			if after := slices.IndexFunc(result, func(w WindowConfig) bool { return w.Name == window.InsertAfter }); after >= 0 {
+				position = after + 1
+			}
+		}
+		result = slices.Insert(result, position, merged)
+		fromBase = slices.Insert(fromBase, position, false)
+	}
+
+	return result
+}
+
+// mergePanes merges overlay into base. Like windows, overlay panes only
+// match panes of base. Unnamed panes match the unnamed pane at the same
+// position of base, so deleting a pane doesn't move the ones after it.
+func mergePanes(base, overlay []PaneConfig) []PaneConfig {
+	result := slices.Clone(base)
+	// origin holds the index in base of each pane of result, or -1 once
+	// the pane was merged or when it was added
+	origin := make([]int, len(result))
+	for i := range origin {
+		origin[i] = i
+	}
+
+	for position, pane := range overlay {
+		if pane.Name == "" && pane.Patch == PatchReplace {
+			// The pane replaces the whole list, and is its first pane
+			// unless it's only the directive
+			result, origin = nil, nil
+			if pane.Patch = ""; reflect.ValueOf(pane).IsZero() {
+				continue
+			}
+		}
+
+		index := -1
+		switch {
+		case pane.Patch == PatchAppend:
+		case pane.Name != "":
+			index = slices.IndexFunc(result, func(p PaneConfig) bool { return p.Name == pane.Name })
+		case position < len(base) && base[position].Name == "":
+			// Unnamed panes are matched by position
+			index = slices.Index(origin, position)
+		}
+		if index >= 0 && origin[index] < 0 {
+			index = -1
+		}
+
+		switch {
+		case pane.Patch == PatchDelete:
+			if index >= 0 {
+				result = slices.Delete(result, index, index+1)
+				origin = slices.Delete(origin, index, index+1)
+			}
+		case index >= 0 && pane.Patch != PatchReplace:
+			result[index] = mergeValue(reflect.ValueOf(result[index]), reflect.ValueOf(pane)).Interface().(PaneConfig)
+			result[index].Patch = ""
+			origin[index] = -1
+		case index >= 0:
+			pane.Patch = ""
+			result[index], origin[index] = pane, -1
+		default:
+			pane.Patch = ""
+			result = append(result, pane)
+			origin = append(origin, -1)
+		}
+	}
+
+	return result
+}
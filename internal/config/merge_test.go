@@ -0,0 +1,286 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergeWindows(t *testing.T) {
+	base := []WindowConfig{
+		{Name: "editor", InitialCommand: "nvim"},
+		{Name: "server", InitialCommand: "make run", Directory: "api"},
+		{Name: "git", InitialCommand: "lazygit"},
+	}
+
+	tests := []struct {
+		name    string
+		overlay []WindowConfig
+		want    []WindowConfig
+	}{
+		{
+			name:    "no overlay",
+			overlay: nil,
+			want:    base,
+		},
+		{
+			name:    "merge by name",
+			overlay: []WindowConfig{{Name: "server", InitialCommand: "go run ."}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "go run .", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+			},
+		},
+		{
+			name:    "new window is appended",
+			overlay: []WindowConfig{{Name: "logs", InitialCommand: "tail -f log"}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+				{Name: "logs", InitialCommand: "tail -f log"},
+			},
+		},
+		{
+			name:    "replace drops the base fields",
+			overlay: []WindowConfig{{Name: "server", InitialCommand: "go run .", Patch: PatchReplace}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "go run ."},
+				{Name: "git", InitialCommand: "lazygit"},
+			},
+		},
+		{
+			name:    "delete",
+			overlay: []WindowConfig{{Name: "server", Patch: PatchDelete}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "git", InitialCommand: "lazygit"},
+			},
+		},
+		{
+			name:    "delete of a missing window",
+			overlay: []WindowConfig{{Name: "missing", Patch: PatchDelete}},
+			want:    base,
+		},
+		{
+			name:    "append keeps both",
+			overlay: []WindowConfig{{Name: "server", InitialCommand: "go run .", Patch: PatchAppend}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+				{Name: "server", InitialCommand: "go run ."},
+			},
+		},
+		{
+			name:    "insert after",
+			overlay: []WindowConfig{{Name: "logs", InitialCommand: "tail -f log", InsertAfter: "editor"}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "logs", InitialCommand: "tail -f log"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+			},
+		},
+		{
+			name:    "insert after moves a merged window",
+			overlay: []WindowConfig{{Name: "git", InitialCommand: "tig", InsertAfter: "editor"}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "git", InitialCommand: "tig"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+			},
+		},
+		{
+			name:    "insert after a missing window appends",
+			overlay: []WindowConfig{{Name: "logs", InsertAfter: "missing"}},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+				{Name: "logs"},
+			},
+		},
+		{
+			name:    "unnamed replace clears the list",
+			overlay: []WindowConfig{{Patch: PatchReplace}, {Name: "shell"}},
+			want:    []WindowConfig{{Name: "shell"}},
+		},
+		{
+			name: "unnamed replace is kept as the first window",
+			overlay: []WindowConfig{
+				{Patch: PatchReplace, Panes: []PaneConfig{{InitialCommand: "fish"}}},
+				{Name: "shell"},
+			},
+			want: []WindowConfig{
+				{Panes: []PaneConfig{{InitialCommand: "fish"}}},
+				{Name: "shell"},
+			},
+		},
+		{
+			name: "same name in one file is kept twice",
+			overlay: []WindowConfig{
+				{Name: "test", InitialCommand: "go test ./..."},
+				{Name: "test", InitialCommand: "npm test"},
+			},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+				{Name: "test", InitialCommand: "go test ./..."},
+				{Name: "test", InitialCommand: "npm test"},
+			},
+		},
+		{
+			name: "same name in one file merges into base once",
+			overlay: []WindowConfig{
+				{Name: "server", InitialCommand: "go run ."},
+				{Name: "server", InitialCommand: "air"},
+			},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "go run .", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+				{Name: "server", InitialCommand: "air"},
+			},
+		},
+		{
+			name: "pane directives of a new window are applied",
+			overlay: []WindowConfig{
+				{Name: "logs", Panes: []PaneConfig{{InitialCommand: "tail -f log", Patch: PatchAppend}}},
+			},
+			want: []WindowConfig{
+				{Name: "editor", InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{Name: "git", InitialCommand: "lazygit"},
+				{Name: "logs", Panes: []PaneConfig{{InitialCommand: "tail -f log"}}},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := mergeWindows(base, test.overlay)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("mergeWindows() =\n%+v\nwant\n%+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergePanes(t *testing.T) {
+	base := []PaneConfig{
+		{InitialCommand: "nvim"},
+		{Name: "server", InitialCommand: "make run", Directory: "api"},
+		{InitialCommand: "htop"},
+	}
+
+	tests := []struct {
+		name    string
+		overlay []PaneConfig
+		want    []PaneConfig
+	}{
+		{
+			name:    "unnamed panes match by position",
+			overlay: []PaneConfig{{InitialCommand: "hx"}},
+			want: []PaneConfig{
+				{InitialCommand: "hx"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{InitialCommand: "htop"},
+			},
+		},
+		{
+			name:    "unnamed pane at a named position is appended",
+			overlay: []PaneConfig{{}, {InitialCommand: "btop"}},
+			want: []PaneConfig{
+				{InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{InitialCommand: "htop"},
+				{InitialCommand: "btop"},
+			},
+		},
+		{
+			name:    "merge by name",
+			overlay: []PaneConfig{{Name: "server", InitialCommand: "go run ."}},
+			want: []PaneConfig{
+				{InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "go run .", Directory: "api"},
+				{InitialCommand: "htop"},
+			},
+		},
+		{
+			name:    "replace",
+			overlay: []PaneConfig{{Name: "server", InitialCommand: "go run .", Patch: PatchReplace}},
+			want: []PaneConfig{
+				{InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "go run ."},
+				{InitialCommand: "htop"},
+			},
+		},
+		{
+			name:    "delete",
+			overlay: []PaneConfig{{Name: "server", Patch: PatchDelete}},
+			want: []PaneConfig{
+				{InitialCommand: "nvim"},
+				{InitialCommand: "htop"},
+			},
+		},
+		{
+			name:    "append",
+			overlay: []PaneConfig{{InitialCommand: "btop", Patch: PatchAppend}},
+			want: []PaneConfig{
+				{InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "make run", Directory: "api"},
+				{InitialCommand: "htop"},
+				{InitialCommand: "btop"},
+			},
+		},
+		{
+			name:    "positions are those of the base after a delete",
+			overlay: []PaneConfig{{Patch: PatchDelete}, {Name: "server", Patch: PatchDelete}, {InitialCommand: "btop"}},
+			want:    []PaneConfig{{InitialCommand: "btop"}},
+		},
+		{
+			name:    "unnamed replace clears the list",
+			overlay: []PaneConfig{{Patch: PatchReplace}, {InitialCommand: "zsh"}},
+			want:    []PaneConfig{{InitialCommand: "zsh"}},
+		},
+		{
+			name:    "unnamed replace is kept as the first pane",
+			overlay: []PaneConfig{{InitialCommand: "fish", Patch: PatchReplace}, {InitialCommand: "zsh"}},
+			want:    []PaneConfig{{InitialCommand: "fish"}, {InitialCommand: "zsh"}},
+		},
+		{
+			name: "same name in one file is kept twice",
+			overlay: []PaneConfig{
+				{Name: "server", InitialCommand: "go run ."},
+				{Name: "server", InitialCommand: "air"},
+			},
+			want: []PaneConfig{
+				{InitialCommand: "nvim"},
+				{Name: "server", InitialCommand: "go run .", Directory: "api"},
+				{InitialCommand: "htop"},
+				{Name: "server", InitialCommand: "air"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got := mergePanes(base, test.overlay)
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("mergePanes() =\n%+v\nwant\n%+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestMergePanesAfterDelete(t *testing.T) {
+	base := []PaneConfig{{InitialCommand: "a"}, {InitialCommand: "b", Directory: "web"}}
+	overlay := []PaneConfig{{Patch: PatchDelete}, {InitialCommand: "x"}}
+	want := []PaneConfig{{InitialCommand: "x", Directory: "web"}}
+	if got := mergePanes(base, overlay); !reflect.DeepEqual(got, want) {
+		t.Errorf("mergePanes() = %+v, want %+v", got, want)
+	}
+}
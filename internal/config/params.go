@@ -0,0 +1,198 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"gopkg.in/yaml.v3"
+)
+
+// TemplateParam declares an input of a parameterised template
+type TemplateParam struct {
+	Name        string      `yaml:"name" desc:"Name the template refers to as {{ .name }}"`
+	Type        string      `yaml:"type,omitempty" desc:"Type of the value (default: string)" enum:"string,int,bool"`
+	Default     interface{} `yaml:"default,omitempty" desc:"Value used when none is given"`
+	Required    bool        `yaml:"required,omitempty" desc:"Whether a value must be given"`
+	Description string      `yaml:"description,omitempty" desc:"Shown when the wizard asks for the value"`
+}
+
+// TemplateParams returns the parameters declared by the named template
+func TemplateParams(templateName string) ([]TemplateParam, error) {
+	templatePath, err := TemplatePath(templateName)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := os.ReadFile(templatePath)
+	if err != nil {
+		return nil, err
+	}
+	return declaredParams(templateName, data)
+}
+
+// templateAction matches the actions of a template
+var templateAction = regexp.MustCompile(`(?s){{.*?}}`)
+
+// declaredParams extracts the params of a template without executing it,
+// as the rest of the file may need values to render: the file is parsed
+// with its template actions blanked out and only the params key decoded.
+func declaredParams(templateName string, data []byte) ([]TemplateParam, error) {
+	// Actions are blanked out keeping their line breaks, so errors point at
+	// the right line
+	blanked := templateAction.ReplaceAllStringFunc(string(data), func(action string) string {
+		return strings.Repeat("\n", strings.Count(action, "\n"))
+	})
+	var doc yaml.Node
+	if err := yaml.Unmarshal([]byte(blanked), &doc); err != nil {
+		return nil, fmt.Errorf("failed to read params of template %s: %v", templateName, err)
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	node := mappingValue(doc.Content[0], "params")
+	if node == nil {
+		return nil, nil
+	}
+
+	var params []TemplateParam
+	if err := node.Decode(&params); err != nil {
+		return nil, fmt.Errorf("failed to read params of template %s: %v", templateName, err)
+	}
+	return params, nil
+}
+
+// renderTemplate executes a template file with the given parameter values
+// (from template_params or --set), checking them against the declared params
+func renderTemplate(templateName string, data []byte, values map[string]interface{}) ([]byte, error) {
+	params, err := declaredParams(templateName, data)
+	if err != nil {
+		return nil, err
+	}
+	if len(params) == 0 {
+		return data, nil
+	}
+
+	resolved, err := resolveParams(templateName, params, values)
+	if err != nil {
+		return nil, err
+	}
+	// for_each references are rendered when the element is expanded
+	for _, field := range forEachFields {
+		if _, ok := resolved[field]; !ok {
+			resolved[field] = "{{ ." + field + " }}"
+		}
+	}
+
+	tmpl, err := template.New(templateName).Option("missingkey=error").Parse(string(data))
+	if err != nil {
+		return nil, err
+	}
+	var rendered bytes.Buffer
+	if err := tmpl.Execute(&rendered, resolved); err != nil {
+		return nil, fmt.Errorf("failed to render template %s: %v", templateName, err)
+	}
+	return rendered.Bytes(), nil
+}
+
+// resolveParams converts values to the declared types, filling in defaults.
+// All problems are reported together. Values for parameters the template
+// doesn't declare are left out; they may belong to a template it extends.
+func resolveParams(templateName string, params []TemplateParam, values map[string]interface{}) (map[string]interface{}, error) {
+	resolved := map[string]interface{}{}
+	var problems []string
+
+	for _, param := range params {
+
+		value, ok := values[param.Name]
+		if !ok {
+			value = param.Default
+		}
+		if value == nil {
+			if param.Required {
+				problems = append(problems, fmt.Sprintf("parameter %s is required", param.Name))
+				continue
+			}
+			value = zeroParam(param.Type)
+		}
+
+		converted, err := convertParam(param.Type, value)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("parameter %s: %v", param.Name, err))
+			continue
+		}
+		resolved[param.Name] = converted
+	}
+
+	if len(problems) > 0 {
+		return nil, fmt.Errorf("template %s: %s", templateName, strings.Join(problems, "; "))
+	}
+	return resolved, nil
+}
+
+func zeroParam(paramType string) interface{} {
+	switch paramType {
+	case "int":
+		return 0
+	case "bool":
+		return false
+	}
+	return ""
+}
+
+// convertParam converts a value from YAML or the command line to the
+// declared parameter type
+func convertParam(paramType string, value interface{}) (interface{}, error) {
+	switch paramType {
+	case "", "string":
+		return fmt.Sprint(value), nil
+	case "int":
+		switch v := value.(type) {
+		case int:
+			return v, nil
+		case string:
+			n, err := strconv.Atoi(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected an int, got %q", v)
+			}
+			return n, nil
+		}
+		return nil, fmt.Errorf("expected an int, got %v", value)
+	case "bool":
+		switch v := value.(type) {
+		case bool:
+			return v, nil
+		case string:
+			b, err := strconv.ParseBool(strings.TrimSpace(v))
+			if err != nil {
+				return nil, fmt.Errorf("expected a bool, got %q", v)
+			}
+			return b, nil
+		}
+		return nil, fmt.Errorf("expected a bool, got %v", value)
+	}
+	return nil, fmt.Errorf("unsupported type %q", paramType)
+}
+
+// Parse converts a value typed by the user to the parameter's type
+func (p TemplateParam) Parse(value string) (interface{}, error) {
+	return convertParam(p.Type, value)
+}
+
+// ParseSet parses --set key=value arguments into parameter values
+func ParseSet(assignments []string) (map[string]interface{}, error) {
+	values := map[string]interface{}{}
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok || key == "" {
+			return nil, errors.New("expected key=value, got " + strconv.Quote(assignment))
+		}
+		values[key] = value
+	}
+	return values, nil
+}
@@ -0,0 +1,152 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDeclaredParams(t *testing.T) {
+	tests := []struct {
+		name     string
+		template string
+		want     []TemplateParam
+		wantErr  bool
+	}{
+		{
+			name:     "no params",
+			template: "session_name: web\n",
+			want:     nil,
+		},
+		{
+			name: "params after other keys",
+			template: `session_name: web
+# the port of the dev server
+params:
+  - name: port
+    type: int
+    default: 3000
+
+  # comments and blank lines stay in the block
+  - name: watch
+    type: bool
+windows:
+  - name: server
+    initial_command: serve -p {{ .port }}
+`,
+			want: []TemplateParam{
+				{Name: "port", Type: "int", Default: 3000},
+				{Name: "watch", Type: "bool"},
+			},
+		},
+		{
+			name: "actions that need values aren't executed",
+			template: `params:
+  - name: port
+    type: int
+    default: 8080
+{{ if gt .port 1024 }}
+windows:
+  - name: server
+{{ end }}
+`,
+			want: []TemplateParam{{Name: "port", Type: "int", Default: 8080}},
+		},
+		{
+			name:     "flow style",
+			template: "params: [{name: port, type: int, default: 3000}, {name: host}]\nsession_name: web\n",
+			want: []TemplateParam{
+				{Name: "port", Type: "int", Default: 3000},
+				{Name: "host"},
+			},
+		},
+		{
+			name: "indented sequence and trailing comments",
+			template: `params: # inputs
+    -   name: port # the dev server
+        type: int
+# a comment at the top level ends nothing
+    -   name: watch
+        type: bool
+windows: []
+`,
+			want: []TemplateParam{
+				{Name: "port", Type: "int"},
+				{Name: "watch", Type: "bool"},
+			},
+		},
+		{
+			name: "params key not at the start of a line",
+			template: `session_name: web
+windows:
+  - name: server
+    params: not the declaration
+`,
+			want: nil,
+		},
+		{
+			name: "actions inside the params block",
+			template: `params:
+  - name: port
+    type: int
+    default: 3000
+{{- if .watch }}
+  - name: watch_dir
+    description: directory to watch, {{ .default_dir }} by default
+{{- end }}
+  - name: watch
+    type: bool
+windows:
+  - name: server
+    initial_command: serve -p {{ .port }}
+`,
+			want: []TemplateParam{
+				{Name: "port", Type: "int", Default: 3000},
+				{Name: "watch_dir", Description: "directory to watch,  by default"},
+				{Name: "watch", Type: "bool"},
+			},
+		},
+		{
+			name:     "params after a multi-line action",
+			template: "{{/*\nparams: commented out\n*/}}\nparams:\n  - name: port\n",
+			want:     []TemplateParam{{Name: "port"}},
+		},
+		{
+			name:     "flow style over several lines",
+			template: "params: [\n  {name: port, type: int},\n  {name: host}\n]\nsession_name: web\n",
+			want:     []TemplateParam{{Name: "port", Type: "int"}, {Name: "host"}},
+		},
+		{
+			name:     "quoted key",
+			template: "\"params\":\n  - name: port\n",
+			want:     []TemplateParam{{Name: "port"}},
+		},
+		{
+			name:     "flow mapping document",
+			template: "{session_name: web, params: [{name: port}]}\n",
+			want:     []TemplateParam{{Name: "port"}},
+		},
+		{
+			name:     "invalid params",
+			template: "params:\n  name: port\n",
+			wantErr:  true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := declaredParams("test", []byte(test.template))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("declaredParams() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("declaredParams() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
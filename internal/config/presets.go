@@ -0,0 +1,122 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// loadUserPresets reads the presets in the presets directory of the
+// configuration directory. Each file can define pane_presets and
+// window_presets, like a config file.
+func (l *loader) loadUserPresets() (Config, error) {
+	var presets Config
+
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return presets, err
+	}
+	files, err := filepath.Glob(filepath.Join(configDir, "presets", "*.yml"))
+	if err != nil {
+		return presets, err
+	}
+
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return presets, err
+		}
+		var fragment Config
+		if err := yaml.Unmarshal(data, &fragment); err != nil {
+			return presets, fmt.Errorf("%s: %v", file, err)
+		}
+		presets = MergeConfigs(presets, Config{
+			PanePresets:   fragment.PanePresets,
+			WindowPresets: fragment.WindowPresets,
+		})
+		l.layers = append(l.layers, layer{kind: "preset", file: file, raw: data, data: data})
+	}
+	return presets, nil
+}
+
+// applyPresets merges the presets that windows and panes refer to with use
+// below them, like a template. Presets can use other presets.
+func applyPresets(cfg Config) (Config, error) {
+	windows := make([]WindowConfig, len(cfg.Windows))
+	for i, window := range cfg.Windows {
+		window, err := usePreset(cfg.WindowPresets, window, "window", nil)
+		if err != nil {
+			return cfg, fmt.Errorf("windows[%d]: %v", i, err)
+		}
+
+		panes := make([]PaneConfig, len(window.Panes))
+		for j, pane := range window.Panes {
+			if panes[j], err = usePreset(cfg.PanePresets, pane, "pane", nil); err != nil {
+				return cfg, fmt.Errorf("windows[%d].panes[%d]: %v", i, j, err)
+			}
+		}
+		window.Panes = panes
+		windows[i] = window
+	}
+	cfg.Windows = windows
+	return cfg, nil
+}
+
+// usePreset resolves the use reference of a window or pane. chain holds the
+// presets being resolved, to detect cycles.
+func usePreset[T WindowConfig | PaneConfig](presets map[string]T, element T, kind string, chain []string) (T, error) {
+	name := presetName(element)
+	if name == "" {
+		return element, nil
+	}
+	if slices.Contains(chain, name) {
+		return element, fmt.Errorf("%s preset cycle: %s -> %s", kind, strings.Join(chain, " -> "), name)
+	}
+
+	preset, ok := presets[name]
+	if !ok {
+		names := make([]string, 0, len(presets))
+		for presetName := range presets {
+			names = append(names, presetName)
+		}
+		sort.Strings(names)
+		if len(names) == 0 {
+			return element, fmt.Errorf("unknown %s preset %q", kind, name)
+		}
+		return element, fmt.Errorf("unknown %s preset %q (available: %s)", kind, name, strings.Join(names, ", "))
+	}
+
+	preset, err := usePreset(presets, preset, kind, append(chain, name))
+	if err != nil {
+		return element, err
+	}
+
+	merged := mergeValue(reflect.ValueOf(preset), reflect.ValueOf(element)).Interface().(T)
+	clearUse(&merged)
+	return merged, nil
+}
+
+func presetName(element interface{}) string {
+	switch e := element.(type) {
+	case WindowConfig:
+		return e.Use
+	case PaneConfig:
+		return e.Use
+	}
+	return ""
+}
+
+func clearUse(element interface{}) {
+	switch e := element.(type) {
+	case *WindowConfig:
+		e.Use = ""
+	case *PaneConfig:
+		e.Use = ""
+	}
+}
@@ -0,0 +1,159 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestApplyPresets(t *testing.T) {
+	panePresets := map[string]PaneConfig{
+		"shell":  {InitialCommand: "zsh", Directory: "src", Env: map[string]string{"SHELL_LEVEL": "1"}},
+		"server": {Use: "shell", InitialCommand: "make run"},
+		"loop-a": {Use: "loop-b"},
+		"loop-b": {Use: "loop-a"},
+	}
+	windowPresets := map[string]WindowConfig{
+		"dev": {Layout: "main-vertical", Panes: []PaneConfig{{Use: "shell"}, {Use: "server"}}},
+	}
+
+	tests := []struct {
+		name    string
+		windows []WindowConfig
+		want    []WindowConfig
+		wantErr string
+	}{
+		{
+			name:    "no use",
+			windows: []WindowConfig{{Name: "editor", Panes: []PaneConfig{{InitialCommand: "nvim"}}}},
+			want:    []WindowConfig{{Name: "editor", Panes: []PaneConfig{{InitialCommand: "nvim"}}}},
+		},
+		{
+			name: "the pane's own fields win",
+			windows: []WindowConfig{{Name: "editor", Panes: []PaneConfig{
+				{Use: "shell", InitialCommand: "nvim", Env: map[string]string{"EDITOR": "nvim"}},
+			}}},
+			want: []WindowConfig{{Name: "editor", Panes: []PaneConfig{
+				{InitialCommand: "nvim", Directory: "src", Env: map[string]string{"SHELL_LEVEL": "1", "EDITOR": "nvim"}},
+			}}},
+		},
+		{
+			name:    "presets using presets",
+			windows: []WindowConfig{{Name: "api", Panes: []PaneConfig{{Use: "server", Directory: "api"}}}},
+			want: []WindowConfig{{Name: "api", Panes: []PaneConfig{
+				{InitialCommand: "make run", Directory: "api", Env: map[string]string{"SHELL_LEVEL": "1"}},
+			}}},
+		},
+		{
+			name:    "window preset first, then the presets of its panes",
+			windows: []WindowConfig{{Name: "web", Use: "dev", Panes: []PaneConfig{{}, {Directory: "web"}}}},
+			want: []WindowConfig{{Name: "web", Layout: "main-vertical", Panes: []PaneConfig{
+				{InitialCommand: "zsh", Directory: "src", Env: map[string]string{"SHELL_LEVEL": "1"}},
+				{InitialCommand: "make run", Directory: "web", Env: map[string]string{"SHELL_LEVEL": "1"}},
+			}}},
+		},
+		{
+			name:    "unknown preset",
+			windows: []WindowConfig{{Name: "web", Use: "prod"}},
+			wantErr: `windows[0]: unknown window preset "prod" (available: dev)`,
+		},
+		{
+			name:    "cycle",
+			windows: []WindowConfig{{Name: "web", Panes: []PaneConfig{{}, {Use: "loop-a"}}}},
+			wantErr: "windows[0].panes[1]: pane preset cycle: loop-a -> loop-b -> loop-a",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			cfg := Config{PanePresets: panePresets, WindowPresets: windowPresets, Windows: test.windows}
+			got, err := applyPresets(cfg)
+			if test.wantErr != "" {
+				if err == nil || err.Error() != test.wantErr {
+					t.Errorf("applyPresets() error = %v, want %q", err, test.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Windows, test.want) {
+				t.Errorf("applyPresets() =\n%+v\nwant\n%+v", got.Windows, test.want)
+			}
+		})
+	}
+}
+
+// TestPresetOrder checks which definition of a preset use refers to: the
+// presets directory, then templates, then the project, each merged over
+// the one before
+func TestPresetOrder(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TMUX_SETUP_HOME", home)
+	for _, dir := range []string{"presets", "templates"} {
+		if err := os.Mkdir(filepath.Join(home, dir), 0755); err != nil {
+			t.Fatal(err)
+		}
+	}
+	writeFile(t, filepath.Join(home, "presets"), "a.yml", `pane_presets:
+  shell:
+    initial_command: bash
+    directory: user
+    env:
+      FROM_USER: "1"
+`)
+	// Later files of the presets directory win
+	writeFile(t, filepath.Join(home, "presets"), "b.yml", `pane_presets:
+  shell:
+    initial_command: sh
+`)
+	writeFile(t, filepath.Join(home, "templates"), "web.yml", `pane_presets:
+  shell:
+    directory: template
+`)
+	dir := t.TempDir()
+
+	tests := []struct {
+		name    string
+		project string
+		want    PaneConfig
+	}{
+		{
+			name:    "presets directory and template",
+			project: "template: web\nwindows:\n  - name: a\n    panes:\n      - use: shell\n",
+			want:    PaneConfig{InitialCommand: "sh", Directory: "template", Env: map[string]string{"FROM_USER": "1"}},
+		},
+		{
+			name:    "presets directory only",
+			project: "windows:\n  - name: a\n    panes:\n      - use: shell\n",
+			want:    PaneConfig{InitialCommand: "sh", Directory: "user", Env: map[string]string{"FROM_USER": "1"}},
+		},
+		{
+			name:    "project preset",
+			project: "template: web\npane_presets:\n  shell:\n    initial_command: fish\nwindows:\n  - name: a\n    panes:\n      - use: shell\n",
+			want:    PaneConfig{InitialCommand: "fish", Directory: "template", Env: map[string]string{"FROM_USER": "1"}},
+		},
+		{
+			name:    "the pane itself",
+			project: "template: web\npane_presets:\n  shell:\n    initial_command: fish\nwindows:\n  - name: a\n    panes:\n      - use: shell\n        directory: pane\n",
+			want:    PaneConfig{InitialCommand: "fish", Directory: "pane", Env: map[string]string{"FROM_USER": "1"}},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			path := writeFile(t, dir, "tmux.conf.yml", test.project)
+			cfg, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(cfg.Windows) != 1 || len(cfg.Windows[0].Panes) != 1 {
+				t.Fatalf("windows = %+v", cfg.Windows)
+			}
+			if got := cfg.Windows[0].Panes[0]; !reflect.DeepEqual(got, test.want) {
+				t.Errorf("pane = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,40 @@
+package config
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// ApplyProfiles merges the named profiles onto cfg in order, with the same
+// rules as templates
+func ApplyProfiles(cfg Config, names []string) (Config, error) {
+	for _, name := range names {
+		profile, ok := cfg.Profiles[name]
+		if !ok {
+			available := ProfileNames(cfg)
+			if len(available) == 0 {
+				return cfg, fmt.Errorf("unknown profile %q (the config has no profiles)", name)
+			}
+			return cfg, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(available, ", "))
+		}
+
+		overlay := Config{
+			Dependencies: profile.Dependencies,
+			Windows:      profile.Windows,
+			Env:          profile.Env,
+		}
+		cfg = MergeConfigs(cfg, overlay)
+	}
+	return cfg, nil
+}
+
+// ProfileNames returns the names of the profiles of cfg, sorted
+func ProfileNames(cfg Config) []string {
+	names := make([]string, 0, len(cfg.Profiles))
+	for name := range cfg.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,362 @@
+package config
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Source describes where a resolved value was defined
+type Source struct {
+	Kind string `json:"kind"` // "preset", "template", "project", "include", "local", "profile", "set" or "default"
+	File string `json:"file,omitempty"`
+	Line int    `json:"line,omitempty"`
+	// Profile is the profile that set the value
+	Profile string `json:"profile,omitempty"`
+	// Param is the template parameter the value was rendered from
+	Param string `json:"param,omitempty"`
+	// Interpolated is set when the value contained ${VAR} references
+	Interpolated bool `json:"interpolated,omitempty"`
+}
+
+func (s Source) String() string {
+	if s.Kind == "set" && s.File == "" {
+		return "--set " + s.Param
+	}
+	if s.Kind == "default" || s.File == "" {
+		return s.Kind
+	}
+
+	location := filepath.Base(s.File)
+	if s.Line > 0 {
+		location += fmt.Sprintf(":%d", s.Line)
+	}
+	var source string
+	switch {
+	case s.Kind == "set":
+		source = fmt.Sprintf("--set %s (template %s)", s.Param, location)
+	case s.Kind == "profile":
+		source = fmt.Sprintf("profile %s (%s)", s.Profile, location)
+	case s.Param != "":
+		source = fmt.Sprintf("%s %s (param %s)", s.Kind, location, s.Param)
+	default:
+		source = s.Kind + " " + location
+	}
+	if s.Interpolated {
+		source += ", interpolated"
+	}
+	return source
+}
+
+// ResolvedValue is a single value of a resolved configuration
+type ResolvedValue struct {
+	Path   string      `json:"path"`
+	Value  interface{} `json:"value"`
+	Source Source      `json:"source"`
+}
+
+// Resolved is a fully resolved configuration together with the origin of
+// each of its values
+type Resolved struct {
+	Config Config `json:"-"`
+	// Files lists the files merged into the configuration, lowest
+	// precedence first
+	Files []string `json:"files"`
+	// Local is the local override file, if one was merged
+	Local  string          `json:"local,omitempty"`
+	Values []ResolvedValue `json:"values"`
+}
+
+// Resolve loads the configuration file at path like LoadWithOptions and
+// records where each of its values came from
+func Resolve(path string, opts Options) (Resolved, error) {
+	config, l, err := loadFile(path, opts)
+	if err != nil {
+		return Resolved{}, err
+	}
+	return resolve(config, l, opts)
+}
+
+// ResolveTemplate is Resolve for a template used directly
+func ResolveTemplate(templateName string, opts Options) (Resolved, error) {
+	config, l, err := loadTemplateFile(templateName, opts)
+	if err != nil {
+		return Resolved{}, err
+	}
+	return resolve(config, l, opts)
+}
+
+// definition is a value as written in one of the layers
+type definition struct {
+	source Source
+	field  string
+	raw    string
+}
+
+// profileDefinition is a value set by a profile, with its path relative
+// to the profile
+type profileDefinition struct {
+	path string
+	def  definition
+}
+
+func resolve(config Config, l *loader, opts Options) (Resolved, error) {
+	resolved := Resolved{Config: config}
+
+	// Later layers take precedence, so the last definition of a path wins
+	definitions := map[string]definition{}
+	var ordered []definition
+	profiles := map[string][]profileDefinition{}
+	for _, layer := range l.layers {
+		resolved.Files = append(resolved.Files, layer.file)
+		if layer.kind == "local" {
+			resolved.Local = layer.file
+		}
+
+		var root yaml.Node
+		if err := yaml.Unmarshal(layer.data, &root); err != nil {
+			return resolved, fmt.Errorf("%s: %v", layer.file, err)
+		}
+		// TOML layers were converted to YAML, so their lines are unknown
+		hasLines := Format(layer.file) != "toml"
+		walkLeaves(&root, "", func(path string, node *yaml.Node) {
+			def := definition{
+				source: Source{Kind: layer.kind, File: layer.file},
+				field:  lastField(path),
+				raw:    node.Value,
+			}
+			if hasLines {
+				def.source.Line = node.Line
+			}
+			if rest, ok := strings.CutPrefix(path, "profiles."); ok {
+				name, path, _ := strings.Cut(rest, ".")
+				profiles[name] = append(profiles[name], profileDefinition{path, def})
+				return
+			}
+			definitions[path] = def
+			ordered = append(ordered, def)
+		})
+	}
+
+	// Selected profiles are applied after all files
+	for _, name := range opts.Profiles {
+		for _, p := range profiles[name] {
+			p.def.source.Kind = "profile"
+			p.def.source.Profile = name
+			definitions[p.path] = p.def
+			ordered = append(ordered, p.def)
+		}
+	}
+
+	// --set values override template_params, which only hold the values of
+	// the files
+	if len(opts.Set) > 0 {
+		params := map[string]interface{}{}
+		for name, value := range config.TemplateParams {
+			params[name] = value
+		}
+		for name, value := range opts.Set {
+			params[name] = value
+		}
+		config.TemplateParams = params
+		resolved.Config.TemplateParams = params
+	}
+
+	var final yaml.Node
+	if err := final.Encode(config); err != nil {
+		return resolved, err
+	}
+
+	var err error
+	walkLeaves(&final, "", func(path string, node *yaml.Node) {
+		if err != nil || skipResolved(path) {
+			return
+		}
+
+		var value interface{}
+		if err = node.Decode(&value); err != nil {
+			return
+		}
+
+		source := Source{Kind: "default"}
+		if name, ok := setParam(path, opts); ok {
+			source = Source{Kind: "set", Param: name}
+		} else if def, ok := definitions[path]; ok {
+			source = def.source
+		} else if def, ok := findDefinition(ordered, lastField(path), node.Value); ok {
+			// Unnamed windows and panes move around when merged, so they are
+			// matched by value instead
+			source = def.source
+		}
+		if source.Kind == "template" {
+			source = paramSource(source, l, opts)
+		}
+		if def, ok := definitions[path]; ok && source.Kind != "set" && strings.Contains(def.raw, "${") {
+			source.Interpolated = true
+		}
+
+		resolved.Values = append(resolved.Values, ResolvedValue{Path: path, Value: value, Source: source})
+	})
+
+	return resolved, err
+}
+
+// skipResolved reports whether a path is left out of the resolved values.
+// Template parameter declarations, profiles, presets and merge directives
+// (template and extends included) have done their job once the
+// configuration is resolved.
+func skipResolved(path string) bool {
+	field := lastField(path)
+	for _, prefix := range []string{"params[", "include[", "extends[", "profiles.", "pane_presets.", "window_presets."} {
+		if strings.HasPrefix(path, prefix) {
+			return true
+		}
+	}
+	return path == "template" || field == "$patch" || field == "insert_after"
+}
+
+// setParam returns the template parameter a template_params path belongs
+// to when its value was given with --set
+func setParam(path string, opts Options) (string, bool) {
+	rest, ok := strings.CutPrefix(path, "template_params.")
+	if !ok {
+		return "", false
+	}
+	name := rest
+	if i := strings.IndexAny(rest, ".["); i >= 0 {
+		name = rest[:i]
+	}
+	_, ok = opts.Set[name]
+	return name, ok
+}
+
+// findDefinition returns the last definition of field with the given value.
+// Values of for_each copies are matched against the text they were
+// rendered from.
+func findDefinition(ordered []definition, field, value string) (definition, bool) {
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if ordered[i].field == field && ordered[i].raw == value {
+			return ordered[i], true
+		}
+	}
+	for i := len(ordered) - 1; i >= 0; i-- {
+		if ordered[i].field == field && renderedFrom(ordered[i].raw, value) {
+			return ordered[i], true
+		}
+	}
+	return definition{}, false
+}
+
+// forEachReference matches the for_each and ${VAR} references in a string
+var forEachReference = regexp.MustCompile(`{{[^}]*}}|\$\{[^}]*\}`)
+
+// renderedFrom reports whether value can be a rendering of raw
+func renderedFrom(raw, value string) bool {
+	if !strings.Contains(raw, "{{") {
+		return false
+	}
+	parts := forEachReference.Split(raw, -1)
+	for i, part := range parts {
+		parts[i] = regexp.QuoteMeta(part)
+	}
+	pattern, err := regexp.Compile("^" + strings.Join(parts, ".*") + "$")
+	return err == nil && pattern.MatchString(value)
+}
+
+// paramSource marks a template value rendered from a template parameter,
+// found by looking for the parameter on the same line of the unrendered
+// template
+func paramSource(source Source, l *loader, opts Options) Source {
+	for _, layer := range l.layers {
+		if layer.file != source.File {
+			continue
+		}
+		lines := strings.Split(string(layer.raw), "\n")
+		if source.Line < 1 || source.Line > len(lines) {
+			return source
+		}
+		for _, match := range paramReference.FindAllStringSubmatch(lines[source.Line-1], -1) {
+			source.Param = match[1]
+			if _, ok := opts.Set[match[1]]; ok {
+				source.Kind = "set"
+				return source
+			}
+		}
+		return source
+	}
+	return source
+}
+
+// paramReference matches {{ .name }} style parameter references
+var paramReference = regexp.MustCompile(`{{[^}]*\.([A-Za-z_][A-Za-z0-9_]*)`)
+
+// walkLeaves calls fn with every non-empty scalar of a document and its
+// path, e.g. windows[editor].panes[0].initial_command. Windows and panes
+// are keyed by name when they have one, and list entries by value, so the
+// same element has the same path in every file.
+func walkLeaves(node *yaml.Node, path string, fn func(path string, node *yaml.Node)) {
+	switch node.Kind {
+	case yaml.DocumentNode:
+		for _, child := range node.Content {
+			walkLeaves(child, path, fn)
+		}
+	case yaml.AliasNode:
+		walkLeaves(node.Alias, path, fn)
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			if key.Value == "<<" {
+				// Merge keys contribute their fields to the mapping itself
+				if value.Kind == yaml.SequenceNode {
+					for _, item := range value.Content {
+						walkLeaves(item, path, fn)
+					}
+				} else {
+					walkLeaves(value, path, fn)
+				}
+				continue
+			}
+			walkLeaves(value, joinPath(path, key.Value), fn)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			walkLeaves(item, fmt.Sprintf("%s[%s]", path, elementKey(item, i)), fn)
+		}
+	case yaml.ScalarNode:
+		if node.Value != "" && node.Tag != "!!null" {
+			fn(path, node)
+		}
+	}
+}
+
+// elementKey identifies a sequence element in a path
+func elementKey(node *yaml.Node, index int) string {
+	if node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	switch node.Kind {
+	case yaml.ScalarNode:
+		return node.Value
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			if node.Content[i].Value == "name" && node.Content[i+1].Value != "" {
+				return node.Content[i+1].Value
+			}
+		}
+	}
+	return fmt.Sprint(index)
+}
+
+// lastField returns the last field name of a path
+func lastField(path string) string {
+	if i := strings.LastIndex(path, "."); i >= 0 {
+		path = path[i+1:]
+	}
+	if i := strings.Index(path, "["); i >= 0 {
+		path = path[:i]
+	}
+	return path
+}
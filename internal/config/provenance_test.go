@@ -0,0 +1,99 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("TMUX_SETUP_HOME", home)
+	templates := filepath.Join(home, "templates")
+	if err := os.MkdirAll(templates, 0755); err != nil {
+		t.Fatal(err)
+	}
+	writeFile(t, templates, "base.yml", "focus_window: 1\n")
+	web := writeFile(t, templates, "web.yml", `params:
+  - name: port
+    type: int
+    default: 3000
+windows:
+  - name: server
+    initial_command: serve -p {{ .port }}
+`)
+	dir := t.TempDir()
+	project := writeFile(t, dir, "tmux.conf.yml", `session_name: web
+extends: base
+template: web
+template_params:
+  port: 9000
+`)
+	bare := writeFile(t, t.TempDir(), "tmux.conf.yml", "template: web\n")
+
+	tests := []struct {
+		name    string
+		project string
+		set     map[string]interface{}
+		// want maps paths to their value and source
+		want map[string][2]string
+	}{
+		{
+			name:    "template_params",
+			project: project,
+			want: map[string][2]string{
+				"session_name":                    {"web", "project tmux.conf.yml:1"},
+				"focus_window":                    {"1", "template base.yml:1"},
+				"template_params.port":            {"9000", "project tmux.conf.yml:5"},
+				"windows[server].initial_command": {"serve -p 9000", "template web.yml:7 (param port)"},
+			},
+		},
+		{
+			name:    "--set wins",
+			project: project,
+			set:     map[string]interface{}{"port": "7000"},
+			want: map[string][2]string{
+				"session_name":                    {"web", "project tmux.conf.yml:1"},
+				"focus_window":                    {"1", "template base.yml:1"},
+				"template_params.port":            {"7000", "--set port"},
+				"windows[server].initial_command": {"serve -p 7000", "--set port (template web.yml:7)"},
+			},
+		},
+		{
+			name:    "--set without template_params",
+			project: bare,
+			set:     map[string]interface{}{"port": "7000"},
+			want: map[string][2]string{
+				"template_params.port":            {"7000", "--set port"},
+				"windows[server].initial_command": {"serve -p 7000", "--set port (template web.yml:7)"},
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			r, err := Resolve(test.project, Options{Set: test.set})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if n := len(r.Files); n < 2 || r.Files[n-2] != web || r.Files[n-1] != test.project {
+				t.Errorf("Files = %q", r.Files)
+			}
+
+			got := map[string][2]string{}
+			for _, value := range r.Values {
+				// template and extends are directives, not settings
+				if value.Path == "template" || value.Path == "extends[base]" {
+					t.Errorf("%s is listed as a resolved value", value.Path)
+				}
+				got[value.Path] = [2]string{fmt.Sprint(value.Value), value.Source.String()}
+			}
+			for path, want := range test.want {
+				if got[path] != want {
+					t.Errorf("%s = %q, want %q", path, got[path], want)
+				}
+			}
+		})
+	}
+}
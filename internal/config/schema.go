@@ -0,0 +1,311 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const schemaDraft = "https://json-schema.org/draft/2020-12/schema"
+
+// Schema returns a JSON Schema for the project config file, generated from
+// the Config type and its desc and enum struct tags
+func Schema() map[string]interface{} {
+	g := &schemaGenerator{defs: map[string]interface{}{}}
+	schema := g.structSchema(reflect.TypeOf(Config{}))
+	schema["$schema"] = schemaDraft
+	schema["title"] = "tmux-setup project configuration"
+	schema["$defs"] = g.defs
+	return schema
+}
+
+type schemaGenerator struct {
+	defs map[string]interface{}
+}
+
+func (g *schemaGenerator) typeSchema(t reflect.Type) map[string]interface{} {
+	switch t.Kind() {
+	case reflect.Pointer:
+		return g.typeSchema(t.Elem())
+	case reflect.String:
+		return map[string]interface{}{"type": "string"}
+	case reflect.Bool:
+		return map[string]interface{}{"type": "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return map[string]interface{}{"type": "integer"}
+	case reflect.Float32, reflect.Float64:
+		return map[string]interface{}{"type": "number"}
+	case reflect.Slice, reflect.Array:
+		return map[string]interface{}{"type": "array", "items": g.typeSchema(t.Elem())}
+	case reflect.Map:
+		return map[string]interface{}{"type": "object", "additionalProperties": g.typeSchema(t.Elem())}
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			// Reserve the name first so recursive types terminate
+			g.defs[t.Name()] = nil
+			g.defs[t.Name()] = g.structSchema(t)
+		}
+		return map[string]interface{}{"$ref": "#/$defs/" + t.Name()}
+	}
+	// interface{} fields accept anything unless overridden
+	return map[string]interface{}{}
+}
+
+func (g *schemaGenerator) structSchema(t reflect.Type) map[string]interface{} {
+	properties := map[string]interface{}{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		name := yamlName(field)
+		if name == "" {
+			continue
+		}
+
+		schema := g.override(t.Name() + "." + field.Name)
+		if schema == nil {
+			schema = g.typeSchema(field.Type)
+		}
+		if desc := field.Tag.Get("desc"); desc != "" {
+			schema["description"] = desc
+		}
+		if enum := field.Tag.Get("enum"); enum != "" {
+			schema["enum"] = stringsToAny(strings.Split(enum, ","))
+		}
+		properties[name] = schema
+	}
+
+	return map[string]interface{}{
+		"type":                 "object",
+		"properties":           properties,
+		"additionalProperties": false,
+	}
+}
+
+// override provides schemas for fields whose Go type doesn't describe the
+// accepted values, keyed by "Type.Field"
+func (g *schemaGenerator) override(field string) map[string]interface{} {
+	switch field {
+	case "WindowConfig.Layout":
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string", "enum": stringsToAny(LayoutPresets)},
+				g.typeSchema(reflect.TypeOf(LayoutConfig{})),
+			},
+		}
+	case "Config.Extends", "Config.Include":
+		return map[string]interface{}{
+			"oneOf": []interface{}{
+				map[string]interface{}{"type": "string"},
+				map[string]interface{}{"type": "array", "items": map[string]interface{}{"type": "string"}},
+			},
+		}
+	case "TemplateParam.Default":
+		return map[string]interface{}{"type": []interface{}{"string", "integer", "boolean"}}
+	case "Config.TmuxHooks":
+		return map[string]interface{}{
+			"type":                 "object",
+			"propertyNames":        map[string]interface{}{"enum": stringsToAny(TmuxHookEvents)},
+			"additionalProperties": map[string]interface{}{"type": "string"},
+		}
+	}
+	return nil
+}
+
+func stringsToAny(values []string) []interface{} {
+	result := make([]interface{}, len(values))
+	for i, v := range values {
+		result[i] = v
+	}
+	return result
+}
+
+// schemaValidator checks YAML nodes against the generated schema
+type schemaValidator struct {
+	file        string
+	defs        map[string]interface{}
+	diagnostics []Diagnostic
+}
+
+// validateSchema checks a parsed config document against Schema
+func validateSchema(path string, root *yaml.Node) []Diagnostic {
+	schema := Schema()
+	v := &schemaValidator{file: path, defs: schema["$defs"].(map[string]interface{})}
+
+	node := root
+	if node.Kind == yaml.DocumentNode {
+		if len(node.Content) == 0 {
+			return nil
+		}
+		node = node.Content[0]
+	}
+	v.check(node, schema, "")
+	return v.diagnostics
+}
+
+func (v *schemaValidator) report(node *yaml.Node, format string, args ...interface{}) {
+	v.diagnostics = append(v.diagnostics, Diagnostic{
+		File:     v.file,
+		Line:     node.Line,
+		Column:   node.Column,
+		Severity: SeverityError,
+		Message:  fmt.Sprintf(format, args...),
+	})
+}
+
+// check validates node against schema; field is the YAML key the node is
+// the value of, used in messages
+func (v *schemaValidator) check(node *yaml.Node, schema map[string]interface{}, field string) {
+	for node.Kind == yaml.AliasNode {
+		node = node.Alias
+	}
+	// An empty value leaves the field unset
+	if node.Kind == yaml.ScalarNode && node.Tag == "!!null" {
+		return
+	}
+
+	if ref, ok := schema["$ref"].(string); ok {
+		v.check(node, v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{}), field)
+		return
+	}
+
+	if branches, ok := schema["oneOf"].([]interface{}); ok {
+		v.checkOneOf(node, branches, field)
+		return
+	}
+
+	if expected, ok := schema["type"].(string); ok && !nodeHasType(node, expected) {
+		v.report(node, "invalid %s: expected %s, got %s", fieldLabel(field), expected, describeNode(node))
+		return
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		v.checkEnum(node, enum, fmt.Sprintf("invalid %s %q", fieldLabel(field), node.Value))
+	}
+
+	switch node.Kind {
+	case yaml.SequenceNode:
+		items, _ := schema["items"].(map[string]interface{})
+		for _, item := range node.Content {
+			if items != nil {
+				v.check(item, items, field)
+			}
+		}
+	case yaml.MappingNode:
+		properties, _ := schema["properties"].(map[string]interface{})
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			// Merge keys pull in anchored mappings, which are checked on their own
+			if key.Value == "<<" {
+				v.check(value, schema, field)
+				continue
+			}
+
+			if names, ok := schema["propertyNames"].(map[string]interface{}); ok {
+				if enum, ok := names["enum"].([]interface{}); ok {
+					v.checkEnum(key, enum, fmt.Sprintf("invalid %s key %q", field, key.Value))
+				}
+			}
+
+			if property, ok := properties[key.Value].(map[string]interface{}); ok {
+				v.check(value, property, key.Value)
+				continue
+			}
+			switch additional := schema["additionalProperties"].(type) {
+			case bool:
+				if !additional {
+					message := fmt.Sprintf("unknown field %q", key.Value)
+					if suggestion := suggest(key.Value, keys(properties)); suggestion != "" {
+						message += fmt.Sprintf(" (did you mean %s?)", suggestion)
+					}
+					v.report(key, "%s", message)
+				}
+			case map[string]interface{}:
+				v.check(value, additional, key.Value)
+			}
+		}
+	}
+}
+
+// checkOneOf reports the problems of the branch that matches the node's
+// type, or a type error if none does
+func (v *schemaValidator) checkOneOf(node *yaml.Node, branches []interface{}, field string) {
+	var expected []string
+	for _, branch := range branches {
+		schema := branch.(map[string]interface{})
+		if ref, ok := schema["$ref"].(string); ok {
+			schema = v.defs[strings.TrimPrefix(ref, "#/$defs/")].(map[string]interface{})
+		}
+		typeName, _ := schema["type"].(string)
+		if nodeHasType(node, typeName) {
+			v.check(node, schema, field)
+			return
+		}
+		expected = append(expected, typeName)
+	}
+	v.report(node, "invalid %s: expected %s, got %s", fieldLabel(field), strings.Join(expected, " or "), describeNode(node))
+}
+
+func (v *schemaValidator) checkEnum(node *yaml.Node, enum []interface{}, message string) {
+	values := make([]string, len(enum))
+	for i, value := range enum {
+		values[i] = fmt.Sprint(value)
+	}
+	if slices.Contains(values, node.Value) {
+		return
+	}
+
+	if suggestion := suggest(node.Value, values); suggestion != "" {
+		message += fmt.Sprintf(" (did you mean %s?)", suggestion)
+	} else {
+		message += fmt.Sprintf(" (expected one of %s)", strings.Join(values, ", "))
+	}
+	v.report(node, "%s", message)
+}
+
+func nodeHasType(node *yaml.Node, typeName string) bool {
+	switch typeName {
+	case "object":
+		return node.Kind == yaml.MappingNode
+	case "array":
+		return node.Kind == yaml.SequenceNode
+	case "string":
+		// Any scalar decodes into a string field
+		return node.Kind == yaml.ScalarNode
+	case "integer":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!int"
+	case "number":
+		return node.Kind == yaml.ScalarNode && (node.Tag == "!!int" || node.Tag == "!!float")
+	case "boolean":
+		return node.Kind == yaml.ScalarNode && node.Tag == "!!bool"
+	}
+	return true
+}
+
+func describeNode(node *yaml.Node) string {
+	switch node.Kind {
+	case yaml.MappingNode:
+		return "a mapping"
+	case yaml.SequenceNode:
+		return "a list"
+	}
+	return fmt.Sprintf("%q", node.Value)
+}
+
+func fieldLabel(field string) string {
+	if field == "" {
+		return "config"
+	}
+	return field
+}
+
+func keys(m map[string]interface{}) []string {
+	result := make([]string, 0, len(m))
+	for key := range m {
+		result = append(result, key)
+	}
+	slices.Sort(result)
+	return result
+}
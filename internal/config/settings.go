@@ -0,0 +1,127 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Settings are the user's preferences, read from config.yml in the
+// configuration directory
+type Settings struct {
+	// SessionName names sessions whose config doesn't. {dir} is replaced
+	// with the name of the project directory.
+	SessionName string `yaml:"session_name"`
+	// Attach controls whether start attaches to the session it created
+	Attach bool `yaml:"attach"`
+	// Socket is the tmux server socket name, as with tmux -L
+	Socket string `yaml:"socket,omitempty"`
+	// Editor opens config files, falling back to $VISUAL and $EDITOR
+	Editor string `yaml:"editor,omitempty"`
+	// TrustedPaths are directories (or globs of directories) whose project
+	// configs don't need to be approved
+	TrustedPaths []string `yaml:"trusted_paths,omitempty"`
+	// DefaultTemplate is used when no project config is found
+	DefaultTemplate string `yaml:"default_template,omitempty"`
+}
+
+// DefaultSettings returns the settings used when config.yml doesn't set them
+func DefaultSettings() Settings {
+	return Settings{
+		SessionName: "dev",
+		Attach:      true,
+	}
+}
+
+// SettingsPath returns the path of the settings file
+func SettingsPath() (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "config.yml"), nil
+}
+
+// LoadSettings reads the settings file. A missing file gives the defaults.
+func LoadSettings() (Settings, error) {
+	settings := DefaultSettings()
+
+	path, err := SettingsPath()
+	if err != nil {
+		return settings, err
+	}
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return settings, nil
+	}
+	if err != nil {
+		return settings, err
+	}
+
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+	if err := decoder.Decode(&settings); err != nil && !errors.Is(err, io.EOF) {
+		return settings, fmt.Errorf("%s: %v", path, err)
+	}
+	return settings, nil
+}
+
+// SessionNameFor returns the session name for a project in dir
+func (s Settings) SessionNameFor(dir string) string {
+	name := strings.ReplaceAll(s.SessionName, "{dir}", filepath.Base(dir))
+	if name == "" {
+		name = DefaultSettings().SessionName
+	}
+	// tmux doesn't allow these in session names
+	return strings.NewReplacer(".", "_", ":", "_").Replace(name)
+}
+
+// EditorCommand returns the editor to open files with
+func (s Settings) EditorCommand() string {
+	for _, editor := range []string{s.Editor, os.Getenv("VISUAL"), os.Getenv("EDITOR")} {
+		if editor != "" {
+			return editor
+		}
+	}
+	return "vi"
+}
+
+// IsTrusted reports whether the config file at path is in one of the
+// trusted paths
+func (s Settings) IsTrusted(path string) bool {
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return false
+	}
+	dir := filepath.Dir(path)
+
+	for _, trusted := range s.TrustedPaths {
+		trusted = expandHome(trusted)
+		if matched, _ := filepath.Match(trusted, dir); matched {
+			return true
+		}
+		if rel, err := filepath.Rel(trusted, dir); err == nil && rel != ".." && !strings.HasPrefix(rel, "../") {
+			return true
+		}
+	}
+	return false
+}
+
+// expandHome replaces a leading ~ with the home directory
+func expandHome(path string) string {
+	rest, ok := strings.CutPrefix(path, "~")
+	if !ok || (rest != "" && rest[0] != '/') {
+		return path
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return homeDir + rest
+}
@@ -0,0 +1,97 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEditorCommand(t *testing.T) {
+	tests := []struct {
+		name    string
+		setting string
+		visual  string
+		editor  string
+		want    string
+	}{
+		{name: "setting wins", setting: "code --wait", visual: "nvim", editor: "nano", want: "code --wait"},
+		{name: "VISUAL before EDITOR", visual: "nvim", editor: "nano", want: "nvim"},
+		{name: "EDITOR", editor: "nano", want: "nano"},
+		{name: "vi when nothing is set", want: "vi"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Setenv("VISUAL", test.visual)
+			t.Setenv("EDITOR", test.editor)
+			settings := Settings{Editor: test.setting}
+			if got := settings.EditorCommand(); got != test.want {
+				t.Errorf("EditorCommand() = %q, want %q", got, test.want)
+			}
+		})
+	}
+}
+
+func TestLoadSettings(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string // "" leaves config.yml out
+		want    Settings
+		wantErr bool
+	}{
+		{name: "no settings file", want: DefaultSettings()},
+		{name: "empty settings file", data: "# nothing yet\n", want: DefaultSettings()},
+		{
+			name: "editor and trusted paths",
+			data: "editor: hx\ntrusted_paths:\n  - ~/src\n",
+			want: Settings{SessionName: "dev", Attach: true, Editor: "hx", TrustedPaths: []string{"~/src"}},
+		},
+		{
+			name: "defaults can be turned off",
+			data: "session_name: '{dir}'\nattach: false\n",
+			want: Settings{SessionName: "{dir}"},
+		},
+		{name: "unknown setting", data: "editr: hx\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			home := t.TempDir()
+			t.Setenv("TMUX_SETUP_HOME", home)
+			if test.data != "" {
+				writeFile(t, home, "config.yml", test.data)
+			}
+
+			got, err := LoadSettings()
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("LoadSettings() = %+v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("LoadSettings() = %+v, want %+v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestSettingsPath(t *testing.T) {
+	dir := t.TempDir()
+
+	t.Setenv("TMUX_SETUP_HOME", "")
+	t.Setenv("XDG_CONFIG_HOME", dir)
+	want := filepath.Join(dir, "tmux-setup", "config.yml")
+	if got, err := SettingsPath(); err != nil || got != want {
+		t.Errorf("SettingsPath() with XDG_CONFIG_HOME = %q, %v, want %q", got, err, want)
+	}
+
+	t.Setenv("TMUX_SETUP_HOME", filepath.Join(dir, "home"))
+	want = filepath.Join(dir, "home", "config.yml")
+	if got, err := SettingsPath(); err != nil || got != want {
+		t.Errorf("SettingsPath() with TMUX_SETUP_HOME = %q, %v, want %q", got, err, want)
+	}
+}
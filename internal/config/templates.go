@@ -1,66 +1,181 @@
 package config
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
 
-	"gopkg.in/yaml.v2"
+	"gopkg.in/yaml.v3"
 )
 
-// LoadTemplate loads a template configuration from the templates directory
-func LoadTemplate(templateName string) (Config, error) {
+// LoadTemplate loads a template configuration from the templates directory,
+// rendering it with the given parameter values. Templates it extends (or
+// names in its own template field) are loaded recursively and merged below it.
+func LoadTemplate(templateName string, values map[string]interface{}) (Config, error) {
+	return newLoader(values).loadTemplates([]string{templateName})
+}
+
+// LoadTemplateWithOptions resolves a template used directly, like
+// LoadWithOptions does for a project file. Relative paths, such as env
+// files, are resolved against the working directory.
+func LoadTemplateWithOptions(templateName string, opts Options) (Config, error) {
+	config, _, err := loadTemplateFile(templateName, opts)
+	return config, err
+}
+
+func loadTemplateFile(templateName string, opts Options) (Config, *loader, error) {
+	l := newLoader(opts.Set)
+	presets, err := l.loadUserPresets()
+	if err != nil {
+		return presets, l, err
+	}
+	config, err := l.loadTemplates([]string{templateName})
+	if err != nil {
+		return config, l, err
+	}
+	config = MergeConfigs(presets, config)
+	if config, err = ApplyProfiles(config, opts.Profiles); err != nil {
+		return config, l, err
+	}
+	if config, err = applyPresets(config); err != nil {
+		return config, l, err
+	}
+	if err := Interpolate(&config, "."); err != nil {
+		return config, l, err
+	}
+	if config, err = expandForEach(config, ".", opts.RunCommands); err != nil {
+		return config, l, err
+	}
+	config, err = ApplyConditions(config, ".", opts.RunCommands)
+	return config, l, err
+}
+
+// loader resolves templates and project files, recording the layers the
+// final configuration is merged from
+type loader struct {
+	values   map[string]interface{}
+	declared map[string]bool
+	layers   []layer
+	// envFiles are the env files the configuration reads
+	envFiles []string
+}
+
+// layer is one file merged into a configuration, in merge order
+type layer struct {
+	kind string // "preset", "template", "project", "include" or "local"
+	file string
+	raw  []byte
+	data []byte // raw after rendering template parameters
+}
+
+func newLoader(values map[string]interface{}) *loader {
+	return &loader{values: values, declared: map[string]bool{}}
+}
+
+// loadTemplates loads and merges the named templates in order, and checks
+// that every parameter value is declared by one of them
+func (l *loader) loadTemplates(templateNames []string) (Config, error) {
+	var config Config
+	for _, templateName := range templateNames {
+		templateConfig, err := l.loadTemplateChain(templateName, nil)
+		if err != nil {
+			return config, err
+		}
+		config = MergeConfigs(config, templateConfig)
+	}
+
+	var unknown []string
+	for name := range l.values {
+		if !l.declared[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return config, fmt.Errorf("unknown template parameters: %s", strings.Join(unknown, ", "))
+	}
+
+	return config, nil
+}
+
+// loadTemplateChain loads a template and the templates it extends. chain
+// holds the templates being loaded, to detect cycles.
+func (l *loader) loadTemplateChain(templateName string, chain []string) (Config, error) {
 	var config Config
 
-	configDir, err := getConfigDir()
+	if slices.Contains(chain, templateName) {
+		return config, fmt.Errorf("template inheritance cycle: %s -> %s", strings.Join(chain, " -> "), templateName)
+	}
+	chain = append(chain, templateName)
+
+	templatePath, err := TemplatePath(templateName)
 	if err != nil {
 		return config, err
 	}
 
-	templatePath := filepath.Join(configDir, "templates", templateName+".yml")
-	data, err := os.ReadFile(templatePath)
+	raw, err := os.ReadFile(templatePath)
 	if err != nil {
 		return config, err
 	}
 
-	err = yaml.Unmarshal(data, &config)
-	return config, err
-}
-
-// MergeConfigs merges template config with user config, preferring user config values
-func MergeConfigs(template, user Config) Config {
-	result := template
+	data, err := renderTemplate(templateName, raw, l.values)
+	if err != nil {
+		return config, err
+	}
 
-	// Override with user values if they exist
-	if user.SessionName != "" {
-		result.SessionName = user.SessionName
+	if err := yaml.Unmarshal(data, &config); err != nil {
+		return config, fmt.Errorf("template %s: %v", templateName, err)
 	}
-	if user.FocusWindow != 0 {
-		result.FocusWindow = user.FocusWindow
+	for _, param := range config.Params {
+		l.declared[param.Name] = true
 	}
 
-	// Merge windows
-	if len(user.Windows) > 0 {
-		result.Windows = user.Windows
+	var base Config
+	for _, parent := range config.Parents() {
+		parentConfig, err := l.loadTemplateChain(parent, chain)
+		if err != nil {
+			return config, err
+		}
+		base = MergeConfigs(base, parentConfig)
 	}
 
-	// Merge defaults
-	if user.Defaults != (GlobalDefaults{}) {
-		result.Defaults = user.Defaults
+	l.layers = append(l.layers, layer{kind: "template", file: templatePath, raw: raw, data: data})
+	return MergeConfigs(base, config), nil
+}
+
+// TemplatePath returns the path of the named template file
+func TemplatePath(templateName string) (string, error) {
+	configDir, err := GetConfigDir()
+	if err != nil {
+		return "", err
 	}
 
-	return result
+	return filepath.Join(configDir, "templates", templateName+".yml"), nil
 }
 
-func getConfigDir() (string, error) {
-	homeDir, err := os.UserHomeDir()
+// ListTemplates returns the names of the available templates
+func ListTemplates() ([]string, error) {
+	configDir, err := GetConfigDir()
 	if err != nil {
-		return "", err
+		return nil, err
 	}
 
-	configDir := filepath.Join(homeDir, ".config", "tmux-setup")
-	if err := os.MkdirAll(configDir, 0755); err != nil {
-		return "", err
+	entries, err := os.ReadDir(filepath.Join(configDir, "templates"))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
 	}
 
-	return configDir, nil
+	var names []string
+	for _, entry := range entries {
+		if name, ok := strings.CutSuffix(entry.Name(), ".yml"); ok && !entry.IsDir() {
+			names = append(names, name)
+		}
+	}
+	return names, nil
 }
@@ -0,0 +1,536 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Severity of a Diagnostic
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Diagnostic is a problem found in a config file. Line and Column are 1-based
+// and zero when the problem can't be tied to a position.
+type Diagnostic struct {
+	File     string
+	Line     int
+	Column   int
+	Severity Severity
+	Message  string
+}
+
+func (d Diagnostic) String() string {
+	position := d.File
+	if d.Line > 0 {
+		position += fmt.Sprintf(":%d:%d", d.Line, d.Column)
+	}
+	return fmt.Sprintf("%s: %s: %s", position, d.Severity, d.Message)
+}
+
+var (
+	yamlLinePattern     = regexp.MustCompile(`^(?:yaml: )?line (\d+): (.*)$`)
+	unknownFieldPattern = regexp.MustCompile(`^field (\S+) not found in type (\S+)$`)
+	badValuePattern     = regexp.MustCompile("^cannot unmarshal \\S+ `([^`]*)` into (\\S+)$")
+)
+
+// Validate checks the config file at path. It decodes the file strictly and
+// checks it against Schema to find unknown fields, type errors and invalid
+// values, then checks the resolved configuration for semantic problems.
+// Templates with params are rendered with the values in opts first. The
+// error is only set when the file can't be read.
+func Validate(path string, opts Options) ([]Diagnostic, error) {
+	data, err := readConfig(path)
+	if err != nil {
+		if _, statErr := os.Stat(path); statErr != nil {
+			return nil, err
+		}
+		return []Diagnostic{{File: path, Severity: SeverityError, Message: err.Error()}}, nil
+	}
+
+	templateName := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	params, err := declaredParams(templateName, data)
+	if err != nil {
+		// A file that isn't YAML is reported at the position of the problem
+		var root yaml.Node
+		if syntaxErr := yaml.Unmarshal(data, &root); syntaxErr != nil {
+			return []Diagnostic{syntaxDiagnostic(path, syntaxErr)}, nil
+		}
+		return []Diagnostic{{File: path, Severity: SeverityError, Message: err.Error()}}, nil
+	}
+	isTemplate := len(params) > 0
+	if isTemplate {
+		if data, err = renderTemplate(templateName, data, opts.Set); err != nil {
+			return []Diagnostic{{File: path, Severity: SeverityError, Message: err.Error()}}, nil
+		}
+	}
+
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return []Diagnostic{syntaxDiagnostic(path, err)}, nil
+	}
+
+	diagnostics := decodeStrict(path, data, &root)
+	diagnostics = appendUnique(diagnostics, validateSchema(path, &root)...)
+
+	files := []sourceFile{{path: path, root: &root}}
+	if !isTemplate {
+		fragments, fragmentDiagnostics := validateIncludes(path, &root, map[string]bool{})
+		files = append(files, fragments...)
+		diagnostics = append(diagnostics, fragmentDiagnostics...)
+	}
+
+	// Type errors leave the rest of the configuration decoded, so the
+	// semantic checks still run on it
+	var cfg Config
+	if isTemplate {
+		err = yaml.Unmarshal(data, &cfg)
+	} else {
+		cfg, err = LoadWithOptions(path, opts)
+	}
+	var typeErr *yaml.TypeError
+	if err != nil && !errors.As(err, &typeErr) {
+		if len(diagnostics) == 0 {
+			diagnostics = append(diagnostics, Diagnostic{File: path, Severity: SeverityError, Message: err.Error()})
+		}
+	} else {
+		diagnostics = append(diagnostics, checkSemantics(path, cfg, files)...)
+	}
+
+	order := map[string]int{}
+	for i, file := range files {
+		order[file.path] = i
+	}
+	for i := range diagnostics {
+		// TOML is checked as the YAML it converts to, whose positions
+		// don't match the file
+		if Format(diagnostics[i].File) == "toml" {
+			diagnostics[i].Line, diagnostics[i].Column = 0, 0
+		}
+	}
+	sort.SliceStable(diagnostics, func(i, j int) bool {
+		if order[diagnostics[i].File] != order[diagnostics[j].File] {
+			return order[diagnostics[i].File] < order[diagnostics[j].File]
+		}
+		return diagnostics[i].Line < diagnostics[j].Line
+	})
+	return diagnostics, nil
+}
+
+// sourceFile is a file the validated configuration is made of
+type sourceFile struct {
+	path string
+	root *yaml.Node
+}
+
+// validateIncludes checks the fragments the file at path includes, and the
+// fragments they include in turn. Cycles are reported by the semantic checks,
+// which load the configuration.
+func validateIncludes(path string, root *yaml.Node, seen map[string]bool) ([]sourceFile, []Diagnostic) {
+	var included struct {
+		Include StringList `yaml:"include"`
+	}
+	if err := root.Decode(&included); err != nil {
+		return nil, nil
+	}
+	fragments, err := resolveIncludes(included.Include, filepath.Dir(path))
+	if err != nil {
+		d := Diagnostic{File: path, Severity: SeverityError, Message: err.Error()}
+		if node := lookupNode(root, "include"); node != nil {
+			d.Line, d.Column = node.Line, node.Column
+		}
+		return nil, []Diagnostic{d}
+	}
+	seen[path] = true
+
+	var files []sourceFile
+	var diagnostics []Diagnostic
+	for _, fragment := range fragments {
+		if seen[fragment] {
+			continue
+		}
+		data, err := readConfig(fragment)
+		if err != nil {
+			diagnostics = append(diagnostics, Diagnostic{File: fragment, Severity: SeverityError, Message: err.Error()})
+			continue
+		}
+		var fragmentRoot yaml.Node
+		if err := yaml.Unmarshal(data, &fragmentRoot); err != nil {
+			diagnostics = append(diagnostics, syntaxDiagnostic(fragment, err))
+			continue
+		}
+		if len(fragmentRoot.Content) == 0 {
+			// an empty fragment is fine
+			continue
+		}
+		diagnostics = append(diagnostics, decodeStrict(fragment, data, &fragmentRoot)...)
+		diagnostics = appendUnique(diagnostics, validateSchema(fragment, &fragmentRoot)...)
+		files = append(files, sourceFile{path: fragment, root: &fragmentRoot})
+
+		nested, nestedDiagnostics := validateIncludes(fragment, &fragmentRoot, seen)
+		files = append(files, nested...)
+		diagnostics = append(diagnostics, nestedDiagnostics...)
+	}
+	return files, diagnostics
+}
+
+// appendUnique appends the diagnostics whose position isn't reported yet, so
+// a problem found by several checks is only listed once
+func appendUnique(diagnostics []Diagnostic, more ...Diagnostic) []Diagnostic {
+	for _, d := range more {
+		duplicate := slices.ContainsFunc(diagnostics, func(existing Diagnostic) bool {
+			return existing.Line == d.Line && existing.Column == d.Column
+		})
+		if !duplicate {
+			diagnostics = append(diagnostics, d)
+		}
+	}
+	return diagnostics
+}
+
+// HasErrors reports whether any of the diagnostics is an error
+func HasErrors(diagnostics []Diagnostic) bool {
+	for _, d := range diagnostics {
+		if d.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func syntaxDiagnostic(path string, err error) Diagnostic {
+	d := Diagnostic{File: path, Severity: SeverityError, Message: err.Error()}
+	if m := yamlLinePattern.FindStringSubmatch(err.Error()); m != nil {
+		d.Line, _ = strconv.Atoi(m[1])
+		d.Column = 1
+		d.Message = m[2]
+	}
+	return d
+}
+
+// decodeStrict decodes data with KnownFields and turns every decoding error
+// into a diagnostic
+func decodeStrict(path string, data []byte, root *yaml.Node) []Diagnostic {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	decoder.KnownFields(true)
+
+	var cfg Config
+	err := decoder.Decode(&cfg)
+
+	var typeErr *yaml.TypeError
+	if !errors.As(err, &typeErr) {
+		if err != nil && err.Error() != "EOF" {
+			return []Diagnostic{syntaxDiagnostic(path, err)}
+		}
+		return nil
+	}
+
+	fields := knownFields(reflect.TypeOf(Config{}))
+	var diagnostics []Diagnostic
+	for _, message := range typeErr.Errors {
+		d := Diagnostic{File: path, Severity: SeverityError, Message: message}
+		m := yamlLinePattern.FindStringSubmatch(message)
+		if m == nil {
+			diagnostics = append(diagnostics, d)
+			continue
+		}
+		d.Line, _ = strconv.Atoi(m[1])
+		d.Column = 1
+		d.Message = m[2]
+
+		if field := unknownFieldPattern.FindStringSubmatch(m[2]); field != nil {
+			name, typeName := field[1], field[2]
+			d.Message = fmt.Sprintf("unknown field %q", name)
+			if suggestion := suggest(name, fields[typeName]); suggestion != "" {
+				d.Message += fmt.Sprintf(" (did you mean %s?)", suggestion)
+			}
+			if key := findScalar(root, d.Line, name); key != nil {
+				d.Column = key.Column
+			}
+		} else if value := badValuePattern.FindStringSubmatch(m[2]); value != nil {
+			d.Message = fmt.Sprintf("invalid value %q, expected %s", value[1], value[2])
+			if node := findScalar(root, d.Line, value[1]); node != nil {
+				d.Column = node.Column
+			}
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// knownFields maps the name of every struct type reachable from t to the
+// YAML field names it accepts
+func knownFields(t reflect.Type) map[string][]string {
+	fields := map[string][]string{}
+
+	var visit func(t reflect.Type)
+	visit = func(t reflect.Type) {
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return
+		}
+		if _, ok := fields[t.String()]; ok {
+			return
+		}
+		fields[t.String()] = nil
+
+		for i := 0; i < t.NumField(); i++ {
+			name := yamlName(t.Field(i))
+			if name == "" {
+				continue
+			}
+			fields[t.String()] = append(fields[t.String()], name)
+			visit(t.Field(i).Type)
+		}
+	}
+	visit(t)
+
+	return fields
+}
+
+// yamlName returns the YAML key of a struct field, or "" if it isn't decoded
+func yamlName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+// suggest returns the candidate closest to name, if it's close enough to be
+// a likely typo
+func suggest(name string, candidates []string) string {
+	best, bestDistance := "", len(name)/3+1
+	for _, candidate := range candidates {
+		if d := levenshtein(name, candidate); d <= bestDistance {
+			best, bestDistance = candidate, d
+		}
+	}
+	return best
+}
+
+func levenshtein(a, b string) int {
+	previous := make([]int, len(b)+1)
+	for j := range previous {
+		previous[j] = j
+	}
+	for i := 1; i <= len(a); i++ {
+		current := make([]int, len(b)+1)
+		current[0] = i
+		for j := 1; j <= len(b); j++ {
+			cost := 1
+			if a[i-1] == b[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous = current
+	}
+	return previous[len(b)]
+}
+
+// findScalar returns the first scalar with the given value on the given line
+func findScalar(node *yaml.Node, line int, value string) *yaml.Node {
+	if node.Kind == yaml.ScalarNode && node.Line == line && node.Value == value {
+		return node
+	}
+	for _, child := range node.Content {
+		if found := findScalar(child, line, value); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// lookupNode follows a path of mapping keys and sequence indexes from the
+// document root. It returns the key node for the last mapping key, so the
+// position points at the field name.
+func lookupNode(root *yaml.Node, path ...interface{}) *yaml.Node {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+
+	var key *yaml.Node
+	for _, step := range path {
+		key = nil
+		switch s := step.(type) {
+		case string:
+			if node.Kind != yaml.MappingNode {
+				return nil
+			}
+			var value *yaml.Node
+			for i := 0; i+1 < len(node.Content); i += 2 {
+				if node.Content[i].Value == s {
+					key, value = node.Content[i], node.Content[i+1]
+					break
+				}
+			}
+			if value == nil {
+				return nil
+			}
+			node = value
+		case int:
+			if node.Kind != yaml.SequenceNode || s >= len(node.Content) {
+				return nil
+			}
+			node = node.Content[s]
+		}
+	}
+
+	if key != nil {
+		return key
+	}
+	return node
+}
+
+// checkSemantics reports values that decode fine but can't work. Each
+// problem is reported in the last of files that sets the value.
+func checkSemantics(path string, cfg Config, files []sourceFile) []Diagnostic {
+	var diagnostics []Diagnostic
+	report := func(severity Severity, message string, nodePath ...interface{}) {
+		d := Diagnostic{File: path, Severity: severity, Message: message}
+		if file, node := locate(files, cfg, nodePath...); node != nil {
+			d.File, d.Line, d.Column = file, node.Line, node.Column
+		}
+		diagnostics = append(diagnostics, d)
+	}
+
+	if focus := cfg.Focus(); focus < 0 || focus > len(cfg.Windows) {
+		report(SeverityError, fmt.Sprintf("focus_window %d is out of range, the session has %d windows", focus, len(cfg.Windows)), "focus_window")
+	}
+
+	baseDir := filepath.Dir(path)
+	if cfg.Defaults.Directory != "" && !directoryExists(baseDir, cfg.Defaults.Directory) {
+		report(SeverityWarning, fmt.Sprintf("directory %q does not exist", cfg.Defaults.Directory), "defaults", "directory")
+	}
+
+	seen := map[string]bool{}
+	for i, window := range cfg.Windows {
+		if window.Name != "" {
+			if seen[window.Name] {
+				report(SeverityError, fmt.Sprintf("duplicate window name %q", window.Name), "windows", i, "name")
+			}
+			seen[window.Name] = true
+		}
+
+		windowDir := JoinDirectory(cfg.Defaults.Directory, window.Directory)
+		if window.Directory != "" && !directoryExists(baseDir, windowDir) {
+			report(SeverityWarning, fmt.Sprintf("directory %q does not exist", windowDir), "windows", i, "directory")
+		}
+
+		for j, pane := range window.Panes {
+			paneDir := JoinDirectory(windowDir, pane.Directory)
+			if pane.Directory != "" && !directoryExists(baseDir, paneDir) {
+				report(SeverityWarning, fmt.Sprintf("directory %q does not exist", paneDir), "windows", i, "panes", j, "directory")
+			}
+		}
+	}
+
+	return diagnostics
+}
+
+// locate finds the node of a value of cfg in the last file that sets it.
+// Named windows are looked up by name, since merging fragments moves them.
+func locate(files []sourceFile, cfg Config, nodePath ...interface{}) (string, *yaml.Node) {
+	if len(nodePath) == 0 {
+		return "", nil
+	}
+	for i := len(files) - 1; i >= 0; i-- {
+		path := nodePath
+		if len(path) > 1 && path[0] == "windows" {
+			name := cfg.Windows[path[1].(int)].Name
+			if name != "" {
+				index := windowIndex(files[i].root, name)
+				if index < 0 {
+					continue
+				}
+				path = append([]interface{}{"windows", index}, path[2:]...)
+			} else if i > 0 {
+				// unnamed windows can only be found by position in the
+				// main file
+				continue
+			}
+		}
+		if node := lookupNode(files[i].root, path...); node != nil {
+			return files[i].path, node
+		}
+	}
+	return "", nil
+}
+
+// windowIndex returns the index of the last window called name in a file,
+// or -1
+func windowIndex(root *yaml.Node, name string) int {
+	node := root
+	if node.Kind == yaml.DocumentNode && len(node.Content) > 0 {
+		node = node.Content[0]
+	}
+	if node.Kind != yaml.MappingNode {
+		return -1
+	}
+
+	var windows *yaml.Node
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if node.Content[i].Value == "windows" {
+			windows = node.Content[i+1]
+		}
+	}
+	if windows == nil || windows.Kind != yaml.SequenceNode {
+		return -1
+	}
+	for i := len(windows.Content) - 1; i >= 0; i-- {
+		window := windows.Content[i]
+		if window.Kind != yaml.MappingNode {
+			continue
+		}
+		for j := 0; j+1 < len(window.Content); j += 2 {
+			if window.Content[j].Value == "name" && window.Content[j+1].Value == name {
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+// directoryExists reports whether dir, relative to baseDir, is a directory.
+// Directories using shell expansion can't be checked and are assumed to exist.
+func directoryExists(baseDir, dir string) bool {
+	if strings.Contains(dir, "$") {
+		return true
+	}
+	if rest, ok := strings.CutPrefix(dir, "~"); ok {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return true
+		}
+		dir = filepath.Join(home, rest)
+	}
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(baseDir, dir)
+	}
+	info, err := os.Stat(dir)
+	return err == nil && info.IsDir()
+}
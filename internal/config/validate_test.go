@@ -0,0 +1,165 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are written to a temporary directory, the first one is
+		// validated
+		files [][2]string
+		want  []string
+	}{
+		{
+			name: "valid",
+			files: [][2]string{{"tmux.conf.yml", `session_name: web
+windows:
+  - name: editor
+    layout: tiled
+    panes:
+      - initial_command: nvim
+`}},
+			want: nil,
+		},
+		{
+			name: "syntax error",
+			files: [][2]string{{"tmux.conf.yml", `windows:
+  - name: editor
+    panes: [
+`}},
+			want: []string{"tmux.conf.yml:3:1: error: did not find expected node content"},
+		},
+		{
+			name: "unknown field with suggestion",
+			files: [][2]string{{"tmux.conf.yml", `windows:
+  - name: editor
+    initial_comand: nvim
+`}},
+			want: []string{`tmux.conf.yml:3:5: error: unknown field "initial_comand" (did you mean initial_command?)`},
+		},
+		{
+			name: "invalid value",
+			files: [][2]string{{"tmux.conf.yml", `windows:
+  - name: editor
+    panes:
+      - refresh_interval: soon
+`}},
+			want: []string{`tmux.conf.yml:4:27: error: invalid value "soon", expected int`},
+		},
+		{
+			name: "invalid layout",
+			files: [][2]string{{"tmux.conf.yml", `windows:
+  - name: editor
+    layout: tilde
+`}},
+			want: []string{`tmux.conf.yml:3:13: error: invalid layout "tilde" (did you mean tiled?)`},
+		},
+		{
+			name: "semantic problems",
+			files: [][2]string{{"tmux.conf.yml", `focus_window: 5
+defaults:
+  directory: .
+windows:
+  - name: editor
+  - name: server
+    directory: missing
+    panes:
+      - directory: api
+  - name: editor
+`}},
+			want: []string{
+				"tmux.conf.yml:1:1: error: focus_window 5 is out of range, the session has 3 windows",
+				`tmux.conf.yml:7:5: warning: directory "missing" does not exist`,
+				`tmux.conf.yml:9:9: warning: directory "missing/api" does not exist`,
+				`tmux.conf.yml:10:5: error: duplicate window name "editor"`,
+			},
+		},
+		{
+			name: "existing directories",
+			files: [][2]string{
+				{"tmux.conf.yml", `windows:
+  - name: server
+    directory: api
+    panes:
+      - directory: .
+      - directory: $HOME
+`},
+				{"api/main.go", "package main\n"},
+			},
+			want: nil,
+		},
+		{
+			name: "problems in an include are reported in the fragment",
+			files: [][2]string{
+				{"tmux.conf.yml", `include: fragment.yml
+windows:
+  - name: editor
+`},
+				{"fragment.yml", `windows:
+  - name: server
+    directory: missing
+    pane: []
+`},
+			},
+			want: []string{
+				`fragment.yml:3:5: warning: directory "missing" does not exist`,
+				`fragment.yml:4:5: error: unknown field "pane" (did you mean panes?)`,
+			},
+		},
+		{
+			name: "missing include",
+			files: [][2]string{{"tmux.conf.yml", `session_name: web
+include: missing.yml
+`}},
+			want: []string{"tmux.conf.yml:2:1: error: include missing.yml: no such file"},
+		},
+		{
+			name: "TOML has no positions",
+			files: [][2]string{{"tmux.conf.toml", `[[windows]]
+name = "editor"
+initial_comand = "nvim"
+`}},
+			want: []string{`tmux.conf.toml: error: unknown field "initial_comand" (did you mean initial_command?)`},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			dir := t.TempDir()
+			t.Setenv("TMUX_SETUP_HOME", filepath.Join(dir, "home"))
+			for _, file := range test.files {
+				path := filepath.Join(dir, file[0])
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(file[1]), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			diagnostics, err := Validate(filepath.Join(dir, test.files[0][0]), Options{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, d := range diagnostics {
+				got = append(got, strings.ReplaceAll(d.String(), dir+string(filepath.Separator), ""))
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("Validate() =\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(test.want, "\n"))
+			}
+		})
+	}
+}
+
+func TestValidateMissingFile(t *testing.T) {
+	if _, err := Validate(filepath.Join(t.TempDir(), "tmux.conf.yml"), Options{}); err == nil {
+		t.Error("Validate() of a missing file succeeded")
+	}
+}
@@ -0,0 +1,336 @@
+// Package detect proposes a configuration for a project from the files in
+// its directory
+package detect
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"github.com/bartosz-skejcik/tmux-setup/internal/importer"
+)
+
+// Proposal is a configuration proposed for a project
+type Proposal struct {
+	Config config.Config
+	// Findings describe what was detected, e.g. "package.json scripts: dev, test"
+	Findings []string
+}
+
+// detector adds what it finds in dir to the proposal
+type detector func(dir string, p *proposal) error
+
+// proposal collects the windows detectors propose, by role, so they can
+// be put in a sensible order
+type proposal struct {
+	Proposal
+	servers  []config.WindowConfig
+	services []config.WindowConfig
+	tests    []config.WindowConfig
+}
+
+func (p *proposal) findf(format string, args ...interface{}) {
+	p.Findings = append(p.Findings, fmt.Sprintf(format, args...))
+}
+
+// detectors run in order; later ones propose servers only if earlier ones
+// didn't, and the first to propose tests wins
+var detectors = []detector{
+	detectProcfile,
+	detectCompose,
+	detectPackageJSON,
+	detectGo,
+	detectCargo,
+	detectMakefile,
+}
+
+// Project proposes a configuration for the project in dir: an editor, a
+// window per dev server, script or service, tests in watch mode and git.
+// editor is the command that opens the editor.
+func Project(dir, editor string) (Proposal, error) {
+	var p proposal
+	for _, detect := range detectors {
+		if err := detect(dir, &p); err != nil {
+			return p.Proposal, err
+		}
+	}
+
+	cfg := &p.Config
+	cfg.Windows = append(cfg.Windows, window("editor", editor+" ."))
+	cfg.Windows = append(cfg.Windows, p.servers...)
+	cfg.Windows = append(cfg.Windows, p.services...)
+	if len(p.tests) > 0 {
+		cfg.Windows = append(cfg.Windows, p.tests[0])
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, ".git")); err == nil {
+		command := "git status"
+		if installed("lazygit") {
+			command = "lazygit"
+		}
+		cfg.Windows = append(cfg.Windows, window("git", command))
+		p.findf(".git")
+	}
+
+	// Detectors can propose windows of the same name, like a Procfile's
+	// test process and go.mod's tests, which merging would fold into one
+	seen := map[string]bool{}
+	for i, window := range cfg.Windows {
+		name := window.Name
+		for n := 2; seen[name]; n++ {
+			name = fmt.Sprintf("%s-%d", window.Name, n)
+		}
+		seen[name] = true
+		cfg.Windows[i].Name = name
+	}
+
+	focus := 1
+	cfg.FocusWindow = &focus
+	return p.Proposal, nil
+}
+
+// window returns a window with a single pane running command
+func window(name, command string) config.WindowConfig {
+	return config.WindowConfig{
+		Name:  name,
+		Panes: []config.PaneConfig{{InitialCommand: command}},
+	}
+}
+
+func installed(command string) bool {
+	_, err := exec.LookPath(command)
+	return err == nil
+}
+
+// readFile returns the contents of a file in dir, or nil if it's missing
+func readFile(dir, name string) ([]byte, error) {
+	data, err := os.ReadFile(filepath.Join(dir, name))
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func detectProcfile(dir string, p *proposal) error {
+	data, err := readFile(dir, "Procfile")
+	if data == nil || err != nil {
+		return err
+	}
+	processes, err := importer.ParseProcfile(data)
+	if err != nil {
+		return fmt.Errorf("Procfile: %v", err)
+	}
+
+	var names []string
+	for _, process := range processes {
+		p.servers = append(p.servers, window(process.Name, process.Command))
+		names = append(names, process.Name)
+	}
+	p.findf("Procfile processes: %s", strings.Join(names, ", "))
+	return nil
+}
+
+// detectCompose proposes a window with the logs of every service, which
+// are started before the session
+func detectCompose(dir string, p *proposal) error {
+	for _, name := range importer.ComposeFileNames {
+		data, err := readFile(dir, name)
+		if err != nil {
+			return err
+		}
+		if data == nil {
+			continue
+		}
+		services, err := importer.ComposeServices(data)
+		if err != nil {
+			return fmt.Errorf("%s: %v", name, err)
+		}
+		if len(services) == 0 {
+			return nil
+		}
+
+		logs := config.WindowConfig{Name: "services", Layout: "tiled"}
+		for _, service := range services {
+			logs.Panes = append(logs.Panes, config.PaneConfig{
+				Name:           service,
+				InitialCommand: "docker compose logs -f " + service,
+			})
+		}
+		p.services = append(p.services, logs)
+		p.Config.Defaults.PreCommand = "docker compose up -d"
+		p.findf("%s services: %s", name, strings.Join(services, ", "))
+		return nil
+	}
+	return nil
+}
+
+// serverScripts are the package.json scripts that run a dev server
+var serverScripts = []string{"dev", "start", "serve", "watch"}
+
+func detectPackageJSON(dir string, p *proposal) error {
+	data, err := readFile(dir, "package.json")
+	if data == nil || err != nil {
+		return err
+	}
+	var pkg struct {
+		Scripts map[string]string `json:"scripts"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return fmt.Errorf("package.json: %v", err)
+	}
+
+	run := packageManager(dir)
+	names := make([]string, 0, len(pkg.Scripts))
+	for name := range pkg.Scripts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	noServers := len(p.servers) == 0
+	for _, name := range names {
+		// start usually runs the production build when there's a dev script
+		base, _, _ := strings.Cut(name, ":")
+		if noServers && slices.Contains(serverScripts, base) && !(name == "start" && pkg.Scripts["dev"] != "") {
+			p.servers = append(p.servers, window(strings.ReplaceAll(name, ":", "-"), run+" "+name))
+		}
+	}
+
+	switch {
+	case pkg.Scripts["test:watch"] != "":
+		p.tests = append(p.tests, window("test", run+" test:watch"))
+	case strings.Contains(pkg.Scripts["test"], "jest"):
+		p.tests = append(p.tests, window("test", run+" test -- --watch"))
+	case pkg.Scripts["test"] != "":
+		// vitest watches by default
+		p.tests = append(p.tests, window("test", run+" test"))
+	}
+
+	finding := "package.json"
+	if len(names) > 0 {
+		finding += " scripts: " + strings.Join(names, ", ")
+	}
+	p.findf("%s", finding)
+	return nil
+}
+
+// packageManager returns the command that runs package.json scripts, from
+// the lock file in dir
+func packageManager(dir string) string {
+	for _, lock := range []struct{ file, run string }{
+		{"pnpm-lock.yaml", "pnpm"},
+		{"yarn.lock", "yarn"},
+		{"bun.lockb", "bun run"},
+		{"bun.lock", "bun run"},
+	} {
+		if _, err := os.Stat(filepath.Join(dir, lock.file)); err == nil {
+			return lock.run
+		}
+	}
+	return "npm run"
+}
+
+func detectGo(dir string, p *proposal) error {
+	data, err := readFile(dir, "go.mod")
+	if data == nil || err != nil {
+		return err
+	}
+	p.findf("go.mod")
+
+	if len(p.servers) == 0 && isMainPackage(dir) {
+		p.servers = append(p.servers, window("run", "go run ."))
+	}
+	switch {
+	case installed("gotestsum"):
+		p.tests = append(p.tests, window("test", "gotestsum --watch"))
+	case installed("watchexec"):
+		p.tests = append(p.tests, window("test", "watchexec -e go -- go test ./..."))
+	default:
+		p.tests = append(p.tests, window("test", "go test ./..."))
+	}
+	return nil
+}
+
+var packageMain = regexp.MustCompile(`(?m)^package main\b`)
+
+// isMainPackage reports whether the Go files in dir are a command
+func isMainPackage(dir string) bool {
+	files, _ := filepath.Glob(filepath.Join(dir, "*.go"))
+	for _, file := range files {
+		if strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		data, err := os.ReadFile(file)
+		if err == nil && packageMain.Match(data) {
+			return true
+		}
+	}
+	return false
+}
+
+func detectCargo(dir string, p *proposal) error {
+	data, err := readFile(dir, "Cargo.toml")
+	if data == nil || err != nil {
+		return err
+	}
+	p.findf("Cargo.toml")
+
+	if _, err := os.Stat(filepath.Join(dir, "src", "main.rs")); err == nil && len(p.servers) == 0 {
+		p.servers = append(p.servers, window("run", "cargo run"))
+	}
+	if installed("cargo-watch") {
+		p.tests = append(p.tests, window("test", "cargo watch -x test"))
+	} else {
+		p.tests = append(p.tests, window("test", "cargo test"))
+	}
+	return nil
+}
+
+var makeTarget = regexp.MustCompile(`(?m)^([A-Za-z0-9][A-Za-z0-9_.-]*)\s*:([^=]|$)`)
+
+// makeServerTargets are the Makefile targets that run a dev server
+var makeServerTargets = []string{"dev", "run", "serve", "start", "watch"}
+
+// detectMakefile proposes the Makefile's dev targets when nothing else runs
+// a server, and its test target when nothing else runs tests
+func detectMakefile(dir string, p *proposal) error {
+	data, err := readFile(dir, "Makefile")
+	if data == nil || err != nil {
+		return err
+	}
+
+	var targets []string
+	for _, m := range makeTarget.FindAllSubmatch(data, -1) {
+		if target := string(m[1]); !slices.Contains(targets, target) {
+			targets = append(targets, target)
+		}
+	}
+
+	if len(p.servers) == 0 {
+		for _, target := range targets {
+			if slices.Contains(makeServerTargets, target) {
+				p.servers = append(p.servers, window(target, "make "+target))
+			}
+		}
+	}
+	if len(p.tests) == 0 {
+		if slices.Contains(targets, "test-watch") {
+			p.tests = append(p.tests, window("test", "make test-watch"))
+		} else if slices.Contains(targets, "test") {
+			p.tests = append(p.tests, window("test", "make test"))
+		}
+	}
+
+	finding := "Makefile"
+	if len(targets) > 0 {
+		finding += " targets: " + strings.Join(targets, ", ")
+	}
+	p.findf("%s", finding)
+	return nil
+}
@@ -0,0 +1,189 @@
+package detect
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// summary lists the windows of a proposal as "name: command" with the
+// commands of its panes
+func summary(p Proposal) []string {
+	var windows []string
+	for _, window := range p.Config.Windows {
+		var commands []string
+		for _, pane := range window.Panes {
+			commands = append(commands, pane.InitialCommand)
+		}
+		windows = append(windows, window.Name+": "+strings.Join(commands, " | "))
+	}
+	return windows
+}
+
+func TestProject(t *testing.T) {
+	tests := []struct {
+		name string
+		// files are written to the project directory
+		files map[string]string
+		// tools are the commands on $PATH
+		tools        []string
+		want         []string
+		wantFindings []string
+		wantPre      string
+	}{
+		{
+			name:         "empty directory",
+			want:         []string{"editor: nvim ."},
+			wantFindings: nil,
+		},
+		{
+			name:         "go command",
+			files:        map[string]string{"go.mod": "module example.com/api\n", "main.go": "package main\n", "main_test.go": "package main\n"},
+			want:         []string{"editor: nvim .", "run: go run .", "test: go test ./..."},
+			wantFindings: []string{"go.mod"},
+		},
+		{
+			name:         "go library with gotestsum",
+			files:        map[string]string{"go.mod": "module example.com/lib\n", "lib.go": "package lib\n", "lib_test.go": "package main\n"},
+			tools:        []string{"gotestsum", "watchexec"},
+			want:         []string{"editor: nvim .", "test: gotestsum --watch"},
+			wantFindings: []string{"go.mod"},
+		},
+		{
+			name: "package.json with pnpm",
+			files: map[string]string{
+				"package.json":   `{"scripts": {"dev": "vite", "start": "node dist", "build": "vite build", "test": "jest", "watch:css": "tailwind -w"}}`,
+				"pnpm-lock.yaml": "",
+			},
+			want:         []string{"editor: nvim .", "dev: pnpm dev", "watch-css: pnpm watch:css", "test: pnpm test -- --watch"},
+			wantFindings: []string{"package.json scripts: build, dev, start, test, watch:css"},
+		},
+		{
+			name:         "start without dev",
+			files:        map[string]string{"package.json": `{"scripts": {"start": "node .", "test:watch": "vitest"}}`},
+			want:         []string{"editor: nvim .", "start: npm run start", "test: npm run test:watch"},
+			wantFindings: []string{"package.json scripts: start, test:watch"},
+		},
+		{
+			name: "Procfile servers win over package.json scripts",
+			files: map[string]string{
+				"Procfile":     "web: bin/rails server\nworker: bundle exec sidekiq\n",
+				"package.json": `{"scripts": {"dev": "vite", "test": "vitest"}}`,
+			},
+			want:         []string{"editor: nvim .", "web: bin/rails server", "worker: bundle exec sidekiq", "test: npm run test"},
+			wantFindings: []string{"Procfile processes: web, worker", "package.json scripts: dev, test"},
+		},
+		{
+			name: "compose services in file order",
+			files: map[string]string{
+				"compose.yaml": "services:\n  db:\n    image: postgres\n  cache:\n    image: redis\n",
+			},
+			want:         []string{"editor: nvim .", "services: docker compose logs -f db | docker compose logs -f cache"},
+			wantFindings: []string{"compose.yaml services: db, cache"},
+			wantPre:      "docker compose up -d",
+		},
+		{
+			name:         "cargo with cargo-watch",
+			files:        map[string]string{"Cargo.toml": "[package]\nname = \"app\"\n", "src/main.rs": "fn main() {}\n"},
+			tools:        []string{"cargo-watch"},
+			want:         []string{"editor: nvim .", "run: cargo run", "test: cargo watch -x test"},
+			wantFindings: []string{"Cargo.toml"},
+		},
+		{
+			name:         "Makefile",
+			files:        map[string]string{"Makefile": "VERSION := 1\n.PHONY: dev test\ndev: build\n\tgo run .\ntest:\n\tgo test ./...\ntest-watch:\n\twatchexec make test\n"},
+			want:         []string{"editor: nvim .", "dev: make dev", "test: make test-watch"},
+			wantFindings: []string{"Makefile targets: dev, test, test-watch"},
+		},
+		{
+			name: "Makefile after go",
+			files: map[string]string{
+				"go.mod":   "module example.com/api\n",
+				"main.go":  "package main\n",
+				"Makefile": "run:\n\tgo run .\ntest:\n\tgo test ./...\n",
+			},
+			want:         []string{"editor: nvim .", "run: go run .", "test: go test ./..."},
+			wantFindings: []string{"go.mod", "Makefile targets: run, test"},
+		},
+		{
+			name: "windows of the same name are numbered",
+			files: map[string]string{
+				"Procfile": "test: rspec\n",
+				"go.mod":   "module example.com/api\n",
+			},
+			want:         []string{"editor: nvim .", "test: rspec", "test-2: go test ./..."},
+			wantFindings: []string{"Procfile processes: test", "go.mod"},
+		},
+		{
+			name:         "git",
+			files:        map[string]string{".git/HEAD": "ref: refs/heads/main\n"},
+			want:         []string{"editor: nvim .", "git: git status"},
+			wantFindings: []string{".git"},
+		},
+		{
+			name:         "git with lazygit",
+			files:        map[string]string{".git/HEAD": "ref: refs/heads/main\n"},
+			tools:        []string{"lazygit"},
+			want:         []string{"editor: nvim .", "git: lazygit"},
+			wantFindings: []string{".git"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			bin := t.TempDir()
+			t.Setenv("PATH", bin)
+			for _, tool := range test.tools {
+				if err := os.WriteFile(filepath.Join(bin, tool), []byte("#!/bin/sh\n"), 0755); err != nil {
+					t.Fatal(err)
+				}
+			}
+			dir := t.TempDir()
+			for name, data := range test.files {
+				path := filepath.Join(dir, name)
+				if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			p, err := Project(dir, "nvim")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := summary(p); !reflect.DeepEqual(got, test.want) {
+				t.Errorf("windows =\n%q\nwant\n%q", got, test.want)
+			}
+			if !reflect.DeepEqual(p.Findings, test.wantFindings) {
+				t.Errorf("findings = %q, want %q", p.Findings, test.wantFindings)
+			}
+			if p.Config.Defaults.PreCommand != test.wantPre {
+				t.Errorf("pre_command = %q, want %q", p.Config.Defaults.PreCommand, test.wantPre)
+			}
+			if p.Config.Focus() != 1 {
+				t.Errorf("focus = %d, want the editor", p.Config.Focus())
+			}
+		})
+	}
+}
+
+func TestProjectErrors(t *testing.T) {
+	for name, data := range map[string]string{
+		"package.json":       "{",
+		"docker-compose.yml": "services: [",
+	} {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Project(dir, "nvim"); err == nil || !strings.HasPrefix(err.Error(), name+": ") {
+				t.Errorf("Project() error = %v, want one naming %s", err, name)
+			}
+		})
+	}
+}
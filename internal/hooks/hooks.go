@@ -1,49 +1,17 @@
 package hooks
 
 import (
-	"flag"
 	"fmt"
-	"log"
+	"io"
+	"os"
 	"os/exec"
 
 	"github.com/bartosz-skejcik/tmux-setup/internal/config"
 )
 
-func GetFlagsFromArgs(args []string) flag.FlagSet {
-	flags := flag.NewFlagSet("tmux-setup", flag.ExitOnError)
-	flags.String("template", "", "Use a template from ~/.config/tmux-setup/templates/")
-	flags.String("create-template", "", "Create a new template using the wizard")
-
-	for i, arg := range args {
-		if arg == "--template" {
-			if args[i+1] == "" {
-				log.Fatalln("Please provide a template name")
-			}
-			flags.Set("template", args[i+1])
-		}
-		if arg == "--create-template" {
-			if args[i+1] == "" {
-				log.Fatalln("Please provide a name for the template")
-			}
-			flags.Set("create-template", args[i+1])
-		}
-	}
-
-	return *flags
-}
-
 func RunPreSessionHooks(cfg config.Config) error {
 	if cfg.Defaults.PreCommand != "" {
-		if err := runCommand(cfg.Defaults.PreCommand); err != nil {
-			return err
-		}
-	}
-	return nil
-}
-
-func RunPostSessionHooks(cfg config.Config) error {
-	if cfg.Defaults.PostCommand != "" {
-		if err := runCommand(cfg.Defaults.PostCommand); err != nil {
+		if err := runCommand(sessionLabel(), "pre_command", cfg.Defaults.PreCommand); err != nil {
 			return err
 		}
 	}
@@ -52,7 +20,7 @@ func RunPostSessionHooks(cfg config.Config) error {
 
 func RunPreWindowHooks(window config.WindowConfig) error {
 	if window.PreCommand != "" {
-		if err := runCommand(window.PreCommand); err != nil {
+		if err := runCommand(windowLabel(window), "pre_command", window.PreCommand); err != nil {
 			return err
 		}
 	}
@@ -61,18 +29,50 @@ func RunPreWindowHooks(window config.WindowConfig) error {
 
 func RunPostWindowHooks(window config.WindowConfig) error {
 	if window.PostCommand != "" {
-		if err := runCommand(window.PostCommand); err != nil {
+		if err := runCommand(windowLabel(window), "post_command", window.PostCommand); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func runCommand(command string) error {
+// runCommand runs a hook command, streaming its output to the terminal and
+// the current run log with a "[scope hook]" prefix on every line
+func runCommand(scope, hook, command string) error {
+	prefix := fmt.Sprintf("[%s %s] ", scope, hook)
+
+	var out io.Writer = os.Stdout
+	if current != nil {
+		out = io.MultiWriter(os.Stdout, current.file)
+		fmt.Fprintf(current.file, "%s$ %s\n", prefix, command)
+	}
+	writer := newPrefixWriter(out, prefix)
+	defer writer.Flush()
+
 	cmd := exec.Command("sh", "-c", command)
-	output, err := cmd.CombinedOutput()
-	if err != nil {
-		return fmt.Errorf("hook command failed: %v\nOutput: %s", err, string(output))
+	cmd.Stdout = writer
+	cmd.Stderr = writer
+	if err := cmd.Run(); err != nil {
+		writer.Flush()
+		if current != nil {
+			fmt.Fprintf(current.file, "%sfailed: %v\n", prefix, err)
+		}
+		return fmt.Errorf("hook command %q failed: %v", command, err)
 	}
 	return nil
 }
+
+func sessionLabel() string {
+	if current != nil {
+		return current.session
+	}
+	return "session"
+}
+
+func windowLabel(window config.WindowConfig) string {
+	name := window.Name
+	if name == "" {
+		name = "window"
+	}
+	return sessionLabel() + ":" + name
+}
@@ -0,0 +1,184 @@
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+)
+
+// MaxLogFiles is the number of run logs kept in the logs directory
+const MaxLogFiles = 20
+
+// run is the hook log of a single tmux-setup invocation
+type run struct {
+	session string
+	file    *os.File
+}
+
+var current *run
+
+// StartRun opens a new log file for the hooks of the given session and
+// removes the oldest logs beyond MaxLogFiles
+func StartRun(sessionName string) (string, error) {
+	dir, err := getLogsDir()
+	if err != nil {
+		return "", err
+	}
+
+	name := fmt.Sprintf("%s-%s.log", time.Now().Format("20060102-150405"), sanitize(sessionName))
+	path := filepath.Join(dir, name)
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return "", err
+	}
+	current = &run{session: sessionName, file: file}
+
+	if err := rotateLogs(dir); err != nil {
+		return path, err
+	}
+	return path, nil
+}
+
+// EndRun closes the current run log
+func EndRun() {
+	if current == nil {
+		return
+	}
+	current.file.Close()
+	current = nil
+}
+
+// ListLogs returns the names of the stored run logs, newest first
+func ListLogs() ([]string, error) {
+	dir, err := getLogsDir()
+	if err != nil {
+		return nil, err
+	}
+	return listLogs(dir)
+}
+
+// ReadLog returns the contents of a run log. "last" selects the newest log.
+func ReadLog(name string) ([]byte, error) {
+	dir, err := getLogsDir()
+	if err != nil {
+		return nil, err
+	}
+
+	if name == "last" {
+		logs, err := listLogs(dir)
+		if err != nil {
+			return nil, err
+		}
+		if len(logs) == 0 {
+			return nil, fmt.Errorf("no hook logs in %s", dir)
+		}
+		name = logs[0]
+	}
+	if !strings.HasSuffix(name, ".log") {
+		name += ".log"
+	}
+
+	return os.ReadFile(filepath.Join(dir, filepath.Base(name)))
+}
+
+func listLogs(dir string) ([]string, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var logs []string
+	for _, entry := range entries {
+		if !entry.IsDir() && strings.HasSuffix(entry.Name(), ".log") {
+			logs = append(logs, entry.Name())
+		}
+	}
+	// Names start with a timestamp, so they sort chronologically
+	sort.Sort(sort.Reverse(sort.StringSlice(logs)))
+	return logs, nil
+}
+
+func rotateLogs(dir string) error {
+	logs, err := listLogs(dir)
+	if err != nil {
+		return err
+	}
+	for i := MaxLogFiles; i < len(logs); i++ {
+		if err := os.Remove(filepath.Join(dir, logs[i])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func getLogsDir() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	logsDir := filepath.Join(configDir, "logs")
+	if err := os.MkdirAll(logsDir, 0755); err != nil {
+		return "", err
+	}
+	return logsDir, nil
+}
+
+func sanitize(name string) string {
+	return strings.Map(func(r rune) rune {
+		if r == '/' || r == os.PathSeparator || r == ' ' {
+			return '_'
+		}
+		return r
+	}, name)
+}
+
+// prefixWriter writes every complete line to the underlying writer with a prefix
+type prefixWriter struct {
+	mu     sync.Mutex
+	out    io.Writer
+	prefix string
+	buf    bytes.Buffer
+}
+
+func newPrefixWriter(out io.Writer, prefix string) *prefixWriter {
+	return &prefixWriter{out: out, prefix: prefix}
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	w.buf.Write(p)
+	for {
+		line, err := w.buf.ReadBytes('\n')
+		if err != nil {
+			// Keep the incomplete line for the next write
+			w.buf.Write(line)
+			break
+		}
+		if _, err := fmt.Fprintf(w.out, "%s%s", w.prefix, line); err != nil {
+			return len(p), err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes out a trailing line that didn't end with a newline
+func (w *prefixWriter) Flush() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	if w.buf.Len() > 0 {
+		fmt.Fprintf(w.out, "%s%s\n", w.prefix, w.buf.String())
+		w.buf.Reset()
+	}
+}
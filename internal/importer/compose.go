@@ -0,0 +1,81 @@
+package importer
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// ComposeFileNames are the names docker compose looks for, in its order
+var ComposeFileNames = []string{"compose.yaml", "compose.yml", "docker-compose.yaml", "docker-compose.yml"}
+
+// ComposeServices returns the names of the services of a compose file, in
+// the order they're defined
+func ComposeServices(data []byte) ([]string, error) {
+	var file struct {
+		Services yaml.Node `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return nil, err
+	}
+	if file.Services.Kind == 0 {
+		return nil, nil
+	}
+	if file.Services.Kind != yaml.MappingNode {
+		return nil, fmt.Errorf("line %d: services must be a mapping", file.Services.Line)
+	}
+
+	var services []string
+	for i := 0; i+1 < len(file.Services.Content); i += 2 {
+		services = append(services, file.Services.Content[i].Value)
+	}
+	return services, nil
+}
+
+// Compose translates a compose file into a window per service, with a pane
+// following its logs and a shell in its container, or with panes into a
+// window with those panes for every service. The services are started
+// before the session. file is the compose file's path relative to the
+// project; docker compose finds the files of ComposeFileNames itself.
+func Compose(data []byte, file string, panes bool) (Result, error) {
+	var result Result
+	services, err := ComposeServices(data)
+	if err != nil {
+		return result, err
+	}
+	if len(services) == 0 {
+		return result, fmt.Errorf("no services")
+	}
+
+	command := "docker compose"
+	if !slices.Contains(ComposeFileNames, file) {
+		if strings.ContainsAny(file, " \t;&|<>$`'\"(){}*?~\\") {
+			file = shellQuote(file)
+		}
+		command += " -f " + file
+	}
+	result.Config.Defaults.PreCommand = command + " up -d"
+
+	var window config.WindowConfig
+	for _, service := range services {
+		logs := config.PaneConfig{Name: "logs", InitialCommand: command + " logs -f " + service}
+		shell := config.PaneConfig{Name: "shell", InitialCommand: command + " exec " + service + " sh"}
+		if !panes {
+			result.Config.Windows = append(result.Config.Windows, config.WindowConfig{
+				Name:  service,
+				Panes: []config.PaneConfig{logs, shell},
+			})
+			continue
+		}
+		logs.Name, shell.Name = service, service+"-shell"
+		window.Panes = append(window.Panes, logs, shell)
+	}
+	if panes {
+		window.Name, window.Layout = "services", "tiled"
+		result.Config.Windows = []config.WindowConfig{window}
+	}
+	return result, nil
+}
@@ -0,0 +1,108 @@
+package importer
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+)
+
+func TestComposeServices(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []string
+		wantErr bool
+	}{
+		{name: "no services", data: "volumes:\n  data: {}\n", want: nil},
+		{name: "file order", data: "services:\n  web: {}\n  db: {}\n  cache: {}\n", want: []string{"web", "db", "cache"}},
+		{name: "services not a mapping", data: "services:\n  - web\n", wantErr: true},
+		{name: "invalid YAML", data: "services: [\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ComposeServices([]byte(test.data))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ComposeServices() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ComposeServices() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestComposeFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/compose.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	window := func(service string) config.WindowConfig {
+		return config.WindowConfig{Name: service, Panes: []config.PaneConfig{
+			{Name: "logs", InitialCommand: "docker compose logs -f " + service},
+			{Name: "shell", InitialCommand: "docker compose exec " + service + " sh"},
+		}}
+	}
+	result, err := Compose(data, "compose.yaml", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "docker compose up -d"; result.Config.Defaults.PreCommand != want {
+		t.Errorf("pre_command = %q, want %q", result.Config.Defaults.PreCommand, want)
+	}
+	if want := []config.WindowConfig{window("web"), window("db"), window("cache")}; !reflect.DeepEqual(result.Config.Windows, want) {
+		t.Errorf("windows =\n%+v\nwant\n%+v", result.Config.Windows, want)
+	}
+
+	result, err = Compose(data, "compose.yaml", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []config.WindowConfig{{Name: "services", Layout: "tiled", Panes: []config.PaneConfig{
+		{Name: "web", InitialCommand: "docker compose logs -f web"},
+		{Name: "web-shell", InitialCommand: "docker compose exec web sh"},
+		{Name: "db", InitialCommand: "docker compose logs -f db"},
+		{Name: "db-shell", InitialCommand: "docker compose exec db sh"},
+		{Name: "cache", InitialCommand: "docker compose logs -f cache"},
+		{Name: "cache-shell", InitialCommand: "docker compose exec cache sh"},
+	}}}
+	if !reflect.DeepEqual(result.Config.Windows, want) {
+		t.Errorf("windows with panes =\n%+v\nwant\n%+v", result.Config.Windows, want)
+	}
+}
+
+func TestComposeFile(t *testing.T) {
+	tests := []struct {
+		file string
+		want string
+	}{
+		{file: "compose.yaml", want: "docker compose up -d"},
+		{file: "docker-compose.yml", want: "docker compose up -d"},
+		{file: "compose.dev.yaml", want: "docker compose -f compose.dev.yaml up -d"},
+		{file: "my compose.yaml", want: "docker compose -f 'my compose.yaml' up -d"},
+		{file: "it's.yaml", want: `docker compose -f 'it'\''s.yaml' up -d`},
+	}
+
+	for _, test := range tests {
+		result, err := Compose([]byte("services:\n  web: {}\n"), test.file, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := result.Config.Defaults.PreCommand; got != test.want {
+			t.Errorf("Compose(%q) pre_command = %q, want %q", test.file, got, test.want)
+		}
+	}
+
+	if _, err := Compose([]byte("services: {}\n"), "compose.yaml", false); err == nil {
+		t.Error("Compose() without services succeeded")
+	}
+}
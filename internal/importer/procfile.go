@@ -0,0 +1,67 @@
+package importer
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+)
+
+// Process is an entry of a Procfile
+type Process struct {
+	Name    string
+	Command string
+}
+
+var procfileLine = regexp.MustCompile(`^([A-Za-z0-9_-]+):\s*(.+)$`)
+
+// ParseProcfile reads the processes of a Procfile, in order. Blank lines
+// and comments are skipped.
+func ParseProcfile(data []byte) ([]Process, error) {
+	var processes []Process
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		m := procfileLine.FindStringSubmatch(line)
+		if m == nil {
+			return nil, fmt.Errorf("line %d: expected name: command", number)
+		}
+		processes = append(processes, Process{Name: m[1], Command: strings.TrimSpace(m[2])})
+	}
+	return processes, scanner.Err()
+}
+
+// Procfile translates a Procfile into a window per process, or with panes
+// into a window with a pane per process
+func Procfile(data []byte, panes bool) (Result, error) {
+	var result Result
+	processes, err := ParseProcfile(data)
+	if err != nil {
+		return result, err
+	}
+	if len(processes) == 0 {
+		return result, fmt.Errorf("no processes")
+	}
+
+	if panes {
+		window := config.WindowConfig{Name: "processes", Layout: "tiled"}
+		for _, process := range processes {
+			window.Panes = append(window.Panes, config.PaneConfig{Name: process.Name, InitialCommand: process.Command})
+		}
+		result.Config.Windows = []config.WindowConfig{window}
+		return result, nil
+	}
+	for _, process := range processes {
+		result.Config.Windows = append(result.Config.Windows, config.WindowConfig{
+			Name:  process.Name,
+			Panes: []config.PaneConfig{{InitialCommand: process.Command}},
+		})
+	}
+	return result, nil
+}
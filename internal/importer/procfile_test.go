@@ -0,0 +1,107 @@
+package importer
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+)
+
+func TestParseProcfile(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    []Process
+		wantErr bool
+	}{
+		{name: "empty", data: "", want: nil},
+		{name: "comments only", data: "# nothing\n\n", want: nil},
+		{
+			name: "processes in order",
+			data: "web: rails s\nworker: sidekiq\n",
+			want: []Process{{"web", "rails s"}, {"worker", "sidekiq"}},
+		},
+		{
+			name: "spaces around the command",
+			data: "  web:    rails s  \n",
+			want: []Process{{"web", "rails s"}},
+		},
+		{
+			name: "colons in the command",
+			data: "css: bin/rails tailwindcss:watch\n",
+			want: []Process{{"css", "bin/rails tailwindcss:watch"}},
+		},
+		{
+			name: "CRLF line endings",
+			data: "web: rails s\r\nworker: sidekiq\r\n",
+			want: []Process{{"web", "rails s"}, {"worker", "sidekiq"}},
+		},
+		{name: "missing command", data: "web:\n", wantErr: true},
+		{name: "missing name", data: ": rails s\n", wantErr: true},
+		{name: "invalid name", data: "web server: rails s\n", wantErr: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := ParseProcfile([]byte(test.data))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("ParseProcfile() = %v, want an error", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, test.want) {
+				t.Errorf("ParseProcfile() = %v, want %v", got, test.want)
+			}
+		})
+	}
+}
+
+func TestProcfileFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/Procfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		panes bool
+		want  []config.WindowConfig
+	}{
+		{
+			panes: false,
+			want: []config.WindowConfig{
+				{Name: "web", Panes: []config.PaneConfig{{InitialCommand: "bundle exec rails server -p $PORT"}}},
+				{Name: "worker", Panes: []config.PaneConfig{{InitialCommand: "bundle exec sidekiq -C config/sidekiq.yml"}}},
+				{Name: "release_phase", Panes: []config.PaneConfig{{InitialCommand: "rake db:migrate"}}},
+				{Name: "css", Panes: []config.PaneConfig{{InitialCommand: "bin/rails tailwindcss:watch # rebuilds on change"}}},
+			},
+		},
+		{
+			panes: true,
+			want: []config.WindowConfig{{Name: "processes", Layout: "tiled", Panes: []config.PaneConfig{
+				{Name: "web", InitialCommand: "bundle exec rails server -p $PORT"},
+				{Name: "worker", InitialCommand: "bundle exec sidekiq -C config/sidekiq.yml"},
+				{Name: "release_phase", InitialCommand: "rake db:migrate"},
+				{Name: "css", InitialCommand: "bin/rails tailwindcss:watch # rebuilds on change"},
+			}}},
+		},
+	}
+
+	for _, test := range tests {
+		result, err := Procfile(data, test.panes)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(result.Config.Windows, test.want) {
+			t.Errorf("Procfile(panes=%t) =\n%+v\nwant\n%+v", test.panes, result.Config.Windows, test.want)
+		}
+	}
+
+	if _, err := Procfile([]byte("# no processes\n"), false); err == nil {
+		t.Error("Procfile() without processes succeeded")
+	}
+}
@@ -0,0 +1,291 @@
+// Package importer translates between the session configs of other tools
+// and tmux-setup configs
+package importer
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Result is a translated configuration
+type Result struct {
+	Name   string // name of the project
+	Root   string // directory of the project, if known
+	Config config.Config
+	// Notes describe what couldn't be translated
+	Notes []string
+}
+
+func (r *Result) notef(format string, args ...interface{}) {
+	r.Notes = append(r.Notes, fmt.Sprintf(format, args...))
+}
+
+// TmuxinatorDir returns the directory tmuxinator keeps its projects in:
+// $TMUXINATOR_CONFIG, $XDG_CONFIG_HOME/tmuxinator, ~/.config/tmuxinator or
+// ~/.tmuxinator, whichever exists first
+func TmuxinatorDir() (string, error) {
+	if dir := os.Getenv("TMUXINATOR_CONFIG"); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{filepath.Join(homeDir, ".config", "tmuxinator"), filepath.Join(homeDir, ".tmuxinator")}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		candidates = append([]string{filepath.Join(xdg, "tmuxinator")}, candidates...)
+	}
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no tmuxinator directory found (tried %s)", strings.Join(candidates, ", "))
+}
+
+// TmuxinatorProjects lists the project files in the tmuxinator directory
+func TmuxinatorProjects() ([]string, error) {
+	dir, err := TmuxinatorDir()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// tmuxinatorHandled are the project options Tmuxinator translates or
+// reports itself; others are reported as unknown
+var tmuxinatorHandled = []string{
+	"name", "project_name", "root", "project_root", "windows", "tabs",
+	"pre_window", "pre_tab", "rbenv", "rvm",
+	"on_project_start", "on_project_first_start", "on_project_restart", "on_project_exit", "on_project_stop", "pre", "post",
+	"startup_window", "startup_pane", "attach", "socket_name", "tmux_options", "tmux_command", "cli_args",
+	"enable_pane_titles", "pane_title_position", "pane_title_format",
+}
+
+// Tmuxinator translates a tmuxinator project file. The root becomes
+// defaults.directory, pre_window is run in every pane before its commands,
+// and the project hooks become pre_command, post_command and tmux_hooks.
+func Tmuxinator(data []byte) (Result, error) {
+	var r Result
+	var project map[string]interface{}
+	if err := yaml.Unmarshal(data, &project); err != nil {
+		return r, err
+	}
+	if bytes.Contains(data, []byte("<%")) {
+		r.notef("ERB tags are not evaluated and were kept as text")
+	}
+
+	cfg := &r.Config
+	r.Name = firstString(project, "name", "project_name")
+	cfg.SessionName = r.Name
+	r.Root = firstString(project, "root", "project_root")
+	cfg.Defaults.Directory = r.Root
+
+	// Commands sent to every pane before its own
+	var preWindow []string
+	if version := scalarString(project["rbenv"]); version != "" {
+		preWindow = append(preWindow, "rbenv shell "+version)
+	}
+	if version := scalarString(project["rvm"]); version != "" {
+		preWindow = append(preWindow, "rvm use "+version)
+	}
+	for _, key := range []string{"pre_window", "pre_tab"} {
+		preWindow = append(preWindow, commandList(&r, key, project[key])...)
+	}
+
+	// on_project_start runs before on_project_first_start, and tmux-setup
+	// only runs pre_command when it creates the session
+	var start []string
+	for _, key := range []string{"pre", "on_project_start", "on_project_first_start"} {
+		start = append(start, commandList(&r, key, project[key])...)
+	}
+	cfg.Defaults.PreCommand = strings.Join(start, "; ")
+	if project["on_project_start"] != nil || project["pre"] != nil {
+		r.notef("on_project_start only runs when the session is created, not when attaching to it")
+	}
+	var stop []string
+	for _, key := range []string{"post", "on_project_stop"} {
+		stop = append(stop, commandList(&r, key, project[key])...)
+	}
+	cfg.Defaults.PostCommand = strings.Join(stop, "; ")
+	if exit := commandList(&r, "on_project_exit", project["on_project_exit"]); len(exit) > 0 {
+		cfg.TmuxHooks = map[string]string{"client-detached": strings.Join(exit, "; ")}
+	}
+
+	windows := project["windows"]
+	if windows == nil {
+		windows = project["tabs"]
+	}
+	windowList, ok := windows.([]interface{})
+	if windows != nil && !ok {
+		return r, fmt.Errorf("windows must be a list")
+	}
+	for i, entry := range windowList {
+		window, err := tmuxinatorWindow(&r, entry, preWindow)
+		if err != nil {
+			return r, fmt.Errorf("windows[%d]: %v", i, err)
+		}
+		cfg.Windows = append(cfg.Windows, window)
+	}
+
+	if startup := scalarString(project["startup_window"]); startup != "" {
+		index := slices.IndexFunc(cfg.Windows, func(w config.WindowConfig) bool { return w.Name == startup })
+		if index >= 0 {
+			focus := index + 1
+			cfg.FocusWindow = &focus
+		} else {
+			r.notef("startup_window %s: windows are only matched by name, set focus_window instead", startup)
+		}
+	}
+
+	unsupported := map[string]string{
+		"on_project_restart":  "there is no hook for attaching to a running session",
+		"startup_pane":        "panes can't be focused",
+		"socket_name":         "set socket in the settings instead",
+		"tmux_options":        "tmux options can't be set per project",
+		"tmux_command":        "tmux is always run as tmux",
+		"cli_args":            "tmux options can't be set per project",
+		"enable_pane_titles":  "pane titles aren't supported",
+		"pane_title_position": "pane titles aren't supported",
+		"pane_title_format":   "pane titles aren't supported",
+	}
+	for _, key := range sortedKeys(project) {
+		if reason, ok := unsupported[key]; ok && project[key] != nil {
+			r.notef("%s: %s", key, reason)
+		} else if !slices.Contains(tmuxinatorHandled, key) {
+			r.notef("%s: unknown option", key)
+		}
+	}
+	if attach, ok := project["attach"].(bool); ok && !attach {
+		r.notef("attach: false: use start -d or attach in the settings instead")
+	}
+
+	return r, nil
+}
+
+// tmuxinatorWindow translates a window, a mapping from its name to a
+// command, a list of commands or its options
+func tmuxinatorWindow(r *Result, entry interface{}, preWindow []string) (config.WindowConfig, error) {
+	var window config.WindowConfig
+	entries, ok := entry.(map[string]interface{})
+	if !ok || len(entries) != 1 {
+		return window, fmt.Errorf("expected a mapping from the window name to its contents")
+	}
+	var value interface{}
+	for name, v := range entries {
+		window.Name, value = name, v
+	}
+
+	options, ok := value.(map[string]interface{})
+	if !ok {
+		// A command or list of commands, run in the only pane
+		commands := append(append([]string{}, preWindow...), commandList(r, "window "+window.Name, value)...)
+		window.Panes = []config.PaneConfig{{InitialCommand: strings.Join(commands, "; ")}}
+		return window, nil
+	}
+
+	window.Directory = scalarString(options["root"])
+	if layout := scalarString(options["layout"]); layout != "" {
+		if slices.Contains(config.LayoutPresets, layout) {
+			window.Layout = layout
+		} else {
+			r.notef("window %s: custom layout %q isn't supported, left out", window.Name, layout)
+		}
+	}
+	if options["synchronize"] != nil {
+		r.notef("window %s: synchronize isn't supported", window.Name)
+	}
+	for _, key := range sortedKeys(options) {
+		if !slices.Contains([]string{"root", "layout", "synchronize", "pre", "panes"}, key) {
+			r.notef("window %s: unknown option %s", window.Name, key)
+		}
+	}
+
+	pre := append(append([]string{}, preWindow...), commandList(r, "window "+window.Name+" pre", options["pre"])...)
+	panes, ok := options["panes"].([]interface{})
+	if options["panes"] != nil && !ok {
+		return window, fmt.Errorf("panes must be a list")
+	}
+	if len(panes) == 0 {
+		panes = []interface{}{nil}
+	}
+	for _, entry := range panes {
+		var pane config.PaneConfig
+		value := entry
+		if named, ok := entry.(map[string]interface{}); ok && len(named) == 1 {
+			for name, v := range named {
+				pane.Name, value = name, v
+			}
+		}
+		commands := append(append([]string{}, pre...), commandList(r, "window "+window.Name+" pane", value)...)
+		pane.InitialCommand = strings.Join(commands, "; ")
+		window.Panes = append(window.Panes, pane)
+	}
+	return window, nil
+}
+
+// commandList returns a command or list of commands. Values that are
+// neither are reported.
+func commandList(r *Result, what string, value interface{}) []string {
+	switch v := value.(type) {
+	case nil:
+		return nil
+	case []interface{}:
+		var commands []string
+		for _, item := range v {
+			if command := scalarString(item); command != "" {
+				commands = append(commands, command)
+			}
+		}
+		return commands
+	case map[string]interface{}:
+		r.notef("%s: expected a command or a list of commands, left out", what)
+		return nil
+	}
+	return []string{scalarString(value)}
+}
+
+// scalarString formats a scalar value, "" for anything else
+func scalarString(value interface{}) string {
+	switch value.(type) {
+	case nil, []interface{}, map[string]interface{}:
+		return ""
+	}
+	return fmt.Sprint(value)
+}
+
+func sortedKeys(values map[string]interface{}) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func firstString(values map[string]interface{}, keys ...string) string {
+	for _, key := range keys {
+		if value := scalarString(values[key]); value != "" {
+			return value
+		}
+	}
+	return ""
+}
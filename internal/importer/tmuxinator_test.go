@@ -0,0 +1,136 @@
+package importer
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+)
+
+func TestTmuxinatorFixture(t *testing.T) {
+	data, err := os.ReadFile("testdata/tmuxinator.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := Tmuxinator(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pre := "rbenv shell 2.7.1; export APP_ENV=dev; "
+	focus := 2
+	want := Result{
+		Name: "blog",
+		Root: "~/src/blog",
+		Config: config.Config{
+			SessionName: "blog",
+			FocusWindow: &focus,
+			Defaults: config.GlobalDefaults{
+				Directory:   "~/src/blog",
+				PreCommand:  "docker compose up -d; bundle install",
+				PostCommand: "docker compose stop",
+			},
+			TmuxHooks: map[string]string{"client-detached": "echo detached"},
+			Windows: []config.WindowConfig{
+				{Name: "editor", Directory: "app", Layout: "main-vertical", Panes: []config.PaneConfig{
+					{InitialCommand: pre + "nvm use; vim"},
+					{InitialCommand: pre + "nvm use; guard"},
+					{Name: "logs", InitialCommand: pre + "nvm use; cd log; tail -f development.log"},
+				}},
+				{Name: "server", Panes: []config.PaneConfig{{InitialCommand: pre + "bundle exec rails s"}}},
+				{Name: "shell", Panes: []config.PaneConfig{{InitialCommand: "rbenv shell 2.7.1; export APP_ENV=dev"}}},
+				{Name: "monitor", Panes: []config.PaneConfig{{InitialCommand: pre + "htop"}}},
+			},
+		},
+		Notes: []string{
+			"on_project_start only runs when the session is created, not when attaching to it",
+			`window monitor: custom layout "5e3a,204x51,0,0{102x51,0,0,1,101x51,103,0,2}" isn't supported, left out`,
+			"window monitor: synchronize isn't supported",
+			"socket_name: set socket in the settings instead",
+			"startup_pane: panes can't be focused",
+		},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Tmuxinator() =\n%+v\nwant\n%+v", got, want)
+	}
+}
+
+func TestTmuxinator(t *testing.T) {
+	tests := []struct {
+		name    string
+		project string
+		windows []config.WindowConfig
+		notes   []string
+		wantErr bool
+	}{
+		{
+			name:    "tabs",
+			project: "tabs:\n  - editor: vim\n",
+			windows: []config.WindowConfig{{Name: "editor", Panes: []config.PaneConfig{{InitialCommand: "vim"}}}},
+		},
+		{
+			name:    "command list",
+			project: "windows:\n  - build:\n      - make\n      - make test\n",
+			windows: []config.WindowConfig{{Name: "build", Panes: []config.PaneConfig{{InitialCommand: "make; make test"}}}},
+		},
+		{
+			name:    "unknown startup window",
+			project: "startup_window: 1\nwindows:\n  - editor: vim\n",
+			windows: []config.WindowConfig{{Name: "editor", Panes: []config.PaneConfig{{InitialCommand: "vim"}}}},
+			notes:   []string{"startup_window 1: windows are only matched by name, set focus_window instead"},
+		},
+		{
+			name:    "unknown options",
+			project: "colour: red\nwindows:\n  - editor:\n      zoom: true\n",
+			windows: []config.WindowConfig{{Name: "editor", Panes: []config.PaneConfig{{}}}},
+			notes:   []string{"window editor: unknown option zoom", "colour: unknown option"},
+		},
+		{
+			name:    "ERB",
+			project: "root: <%= ENV['HOME'] %>/src\n",
+			notes:   []string{"ERB tags are not evaluated and were kept as text"},
+		},
+		{
+			name:    "attach false",
+			project: "attach: false\n",
+			notes:   []string{"attach: false: use start -d or attach in the settings instead"},
+		},
+		{
+			name:    "windows not a list",
+			project: "windows: vim\n",
+			wantErr: true,
+		},
+		{
+			name:    "window with two names",
+			project: "windows:\n  - editor: vim\n    shell: zsh\n",
+			wantErr: true,
+		},
+		{
+			name:    "panes not a list",
+			project: "windows:\n  - editor:\n      panes: vim\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Tmuxinator([]byte(test.project))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Tmuxinator() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Config.Windows, test.windows) {
+				t.Errorf("windows =\n%+v\nwant\n%+v", got.Config.Windows, test.windows)
+			}
+			if !reflect.DeepEqual(got.Notes, test.notes) {
+				t.Errorf("notes =\n%q\nwant\n%q", got.Notes, test.notes)
+			}
+		})
+	}
+}
@@ -0,0 +1,360 @@
+package importer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// TmuxpSession is a tmuxp session file. Commands are a string, a list of
+// strings or a list of {cmd: ...} mappings.
+type TmuxpSession struct {
+	SessionName        string                 `yaml:"session_name" json:"session_name"`
+	StartDirectory     string                 `yaml:"start_directory,omitempty" json:"start_directory,omitempty"`
+	BeforeScript       string                 `yaml:"before_script,omitempty" json:"before_script,omitempty"`
+	ShellCommandBefore interface{}            `yaml:"shell_command_before,omitempty" json:"shell_command_before,omitempty"`
+	Environment        map[string]string      `yaml:"environment,omitempty" json:"environment,omitempty"`
+	Options            map[string]interface{} `yaml:"options,omitempty" json:"options,omitempty"`
+	GlobalOptions      map[string]interface{} `yaml:"global_options,omitempty" json:"global_options,omitempty"`
+	Windows            []TmuxpWindow          `yaml:"windows" json:"windows"`
+}
+
+type TmuxpWindow struct {
+	WindowName         string                 `yaml:"window_name" json:"window_name"`
+	StartDirectory     string                 `yaml:"start_directory,omitempty" json:"start_directory,omitempty"`
+	Layout             string                 `yaml:"layout,omitempty" json:"layout,omitempty"`
+	Focus              interface{}            `yaml:"focus,omitempty" json:"focus,omitempty"`
+	Options            map[string]interface{} `yaml:"options,omitempty" json:"options,omitempty"`
+	OptionsAfter       map[string]interface{} `yaml:"options_after,omitempty" json:"options_after,omitempty"`
+	ShellCommandBefore interface{}            `yaml:"shell_command_before,omitempty" json:"shell_command_before,omitempty"`
+	Environment        map[string]string      `yaml:"environment,omitempty" json:"environment,omitempty"`
+	Panes              []interface{}          `yaml:"panes" json:"panes"`
+}
+
+type TmuxpPane struct {
+	ShellCommand   interface{}       `yaml:"shell_command,omitempty" json:"shell_command,omitempty"`
+	StartDirectory string            `yaml:"start_directory,omitempty" json:"start_directory,omitempty"`
+	Focus          interface{}       `yaml:"focus,omitempty" json:"focus,omitempty"`
+	Environment    map[string]string `yaml:"environment,omitempty" json:"environment,omitempty"`
+}
+
+// TmuxpDir returns the directory tmuxp keeps its sessions in:
+// $TMUXP_CONFIGDIR, $XDG_CONFIG_HOME/tmuxp, ~/.config/tmuxp or ~/.tmuxp,
+// whichever exists first
+func TmuxpDir() (string, error) {
+	if dir := os.Getenv("TMUXP_CONFIGDIR"); dir != "" {
+		return dir, nil
+	}
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+
+	candidates := []string{filepath.Join(homeDir, ".config", "tmuxp"), filepath.Join(homeDir, ".tmuxp")}
+	if xdg := os.Getenv("XDG_CONFIG_HOME"); xdg != "" {
+		candidates = append([]string{filepath.Join(xdg, "tmuxp")}, candidates...)
+	}
+	for _, dir := range candidates {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return dir, nil
+		}
+	}
+	return "", fmt.Errorf("no tmuxp directory found (tried %s)", strings.Join(candidates, ", "))
+}
+
+// TmuxpSessions lists the session files in the tmuxp directory
+func TmuxpSessions() ([]string, error) {
+	dir, err := TmuxpDir()
+	if err != nil {
+		return nil, err
+	}
+	var files []string
+	for _, pattern := range []string{"*.yml", "*.yaml", "*.json"} {
+		matches, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+	sort.Strings(files)
+	return files, nil
+}
+
+// Tmuxp translates a tmuxp session file, in YAML or JSON. start_directory
+// becomes defaults.directory and shell_command_before is run in every pane
+// before its commands.
+func Tmuxp(data []byte) (Result, error) {
+	var r Result
+	var session TmuxpSession
+	var known map[string]interface{}
+	if err := yaml.Unmarshal(data, &session); err != nil {
+		return r, err
+	}
+	if err := yaml.Unmarshal(data, &known); err != nil {
+		return r, err
+	}
+	for _, key := range sortedKeys(known) {
+		if !slices.Contains([]string{"session_name", "start_directory", "before_script", "shell_command_before",
+			"environment", "options", "global_options", "windows"}, key) {
+			r.notef("%s isn't supported", key)
+		}
+	}
+	if len(session.Options) > 0 || len(session.GlobalOptions) > 0 {
+		r.notef("tmux options can't be set per project")
+	}
+
+	cfg := &r.Config
+	r.Name = session.SessionName
+	r.Root = session.StartDirectory
+	cfg.SessionName = session.SessionName
+	cfg.Defaults.Directory = session.StartDirectory
+	cfg.Defaults.PreCommand = session.BeforeScript
+	cfg.Env = session.Environment
+	before := tmuxpCommands(&r, "shell_command_before", session.ShellCommandBefore)
+
+	for i, w := range session.Windows {
+		window := config.WindowConfig{
+			Name:      w.WindowName,
+			Directory: w.StartDirectory,
+			Env:       w.Environment,
+		}
+		label := w.WindowName
+		if label == "" {
+			label = fmt.Sprintf("window %d", i+1)
+		}
+
+		if w.Layout != "" {
+			if slices.Contains(config.LayoutPresets, w.Layout) {
+				window.Layout = w.Layout
+			} else {
+				r.notef("%s: custom layout %q isn't supported, left out", label, w.Layout)
+			}
+		}
+		if isTrue(w.Focus) {
+			focus := i + 1
+			cfg.FocusWindow = &focus
+		}
+		if len(w.Options) > 0 || len(w.OptionsAfter) > 0 {
+			r.notef("%s: window options aren't supported", label)
+		}
+		if windows, ok := known["windows"].([]interface{}); ok {
+			if options, ok := windows[i].(map[string]interface{}); ok {
+				for _, key := range sortedKeys(options) {
+					if !slices.Contains([]string{"window_name", "start_directory", "layout", "focus", "options",
+						"options_after", "shell_command_before", "environment", "panes"}, key) {
+						r.notef("%s: %s isn't supported", label, key)
+					}
+				}
+			}
+		}
+
+		windowBefore := append(append([]string{}, before...), tmuxpCommands(&r, label+" shell_command_before", w.ShellCommandBefore)...)
+		panes := w.Panes
+		if len(panes) == 0 {
+			panes = []interface{}{nil}
+		}
+		for j, p := range panes {
+			pane, err := tmuxpPane(&r, fmt.Sprintf("%s, pane %d", label, j+1), p)
+			if err != nil {
+				return r, fmt.Errorf("windows[%d].panes[%d]: %v", i, j, err)
+			}
+			commands := append(append([]string{}, windowBefore...), pane.InitialCommand)
+			if pane.InitialCommand == "" {
+				commands = commands[:len(commands)-1]
+			}
+			pane.InitialCommand = strings.Join(commands, "; ")
+			window.Panes = append(window.Panes, pane)
+		}
+		cfg.Windows = append(cfg.Windows, window)
+	}
+	return r, nil
+}
+
+// tmuxpPane translates a pane: a command, an empty pane, or a mapping
+func tmuxpPane(r *Result, label string, value interface{}) (config.PaneConfig, error) {
+	var pane config.PaneConfig
+	switch v := value.(type) {
+	case nil:
+		return pane, nil
+	case string:
+		if v != "blank" && v != "pane" {
+			pane.InitialCommand = v
+		}
+		return pane, nil
+	case map[string]interface{}:
+		data, err := yaml.Marshal(v)
+		if err != nil {
+			return pane, err
+		}
+		var p TmuxpPane
+		if err := yaml.Unmarshal(data, &p); err != nil {
+			return pane, err
+		}
+		for _, key := range sortedKeys(v) {
+			if !slices.Contains([]string{"shell_command", "start_directory", "focus", "environment"}, key) {
+				r.notef("%s: %s isn't supported", label, key)
+			}
+		}
+		if isTrue(p.Focus) {
+			r.notef("%s: panes can't be focused", label)
+		}
+		pane.Directory = p.StartDirectory
+		pane.Env = p.Environment
+		pane.InitialCommand = strings.Join(tmuxpCommands(r, label, p.ShellCommand), "; ")
+		return pane, nil
+	}
+	return pane, fmt.Errorf("expected a command or a mapping")
+}
+
+// tmuxpCommands returns a command or list of commands
+func tmuxpCommands(r *Result, label string, value interface{}) []string {
+	items, ok := value.([]interface{})
+	if !ok {
+		items = []interface{}{value}
+	}
+
+	var commands []string
+	for _, item := range items {
+		if m, ok := item.(map[string]interface{}); ok {
+			if enter, ok := m["enter"].(bool); ok && !enter {
+				r.notef("%s: commands are always run, enter: false isn't supported", label)
+			}
+			item = m["cmd"]
+		}
+		if command := scalarString(item); command != "" {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+func isTrue(value interface{}) bool {
+	switch v := value.(type) {
+	case bool:
+		return v
+	case string:
+		return v == "true"
+	}
+	return false
+}
+
+// ExportTmuxp translates a resolved configuration into a tmuxp session.
+// The notes describe what tmuxp can't express.
+func ExportTmuxp(cfg config.Config) (TmuxpSession, []string) {
+	var r Result
+	session := TmuxpSession{
+		SessionName:    cfg.SessionName,
+		StartDirectory: cfg.Defaults.Directory,
+		Environment:    cfg.Env,
+	}
+
+	// before_script isn't run by a shell
+	if command := cfg.Defaults.PreCommand; command != "" {
+		if strings.ContainsAny(command, ";&|<>$`'\"(){}*?~") {
+			command = "sh -c " + shellQuote(command)
+		}
+		session.BeforeScript = command
+	}
+	if cfg.Defaults.PostCommand != "" {
+		r.notef("defaults.post_command: tmuxp has no hook for closing the session")
+	}
+	if len(cfg.TmuxHooks) > 0 {
+		r.notef("tmux_hooks: tmuxp can't bind tmux hooks")
+	}
+	if len(cfg.Dependencies) > 0 {
+		r.notef("dependencies: tmuxp doesn't check dependencies")
+	}
+
+	for i, window := range cfg.Windows {
+		w := TmuxpWindow{
+			WindowName:     window.Name,
+			StartDirectory: window.Directory,
+			Environment:    window.Env,
+		}
+		label := window.Name
+		if label == "" {
+			label = fmt.Sprintf("window %d", i+1)
+		}
+
+		switch layout := window.Layout.(type) {
+		case nil:
+		case string:
+			w.Layout = layout
+		default:
+			r.notef("%s: custom layouts can't be expressed, left out", label)
+		}
+		if cfg.Focus() == i+1 {
+			w.Focus = true
+		}
+		if window.PreCommand != "" || window.PostCommand != "" {
+			r.notef("%s: pre_command and post_command can't be expressed", label)
+		}
+		if window.ForEach != nil {
+			r.notef("%s: for_each with a command wasn't expanded", label)
+		}
+
+		// Only the commands of panes are run, so the session starts the same
+		// in tmuxp
+		if window.InitialCommand != "" {
+			r.notef("%s: initial_command isn't run for windows, only for panes", label)
+		}
+		panes := window.Panes
+		if len(panes) == 0 {
+			panes = []config.PaneConfig{{}}
+		}
+		windowDir := config.JoinDirectory(cfg.Defaults.Directory, window.Directory)
+		for j, pane := range panes {
+			paneLabel := fmt.Sprintf("%s, pane %d", label, j+1)
+			p := TmuxpPane{Environment: pane.Env}
+			if pane.Directory != "" {
+				p.StartDirectory = config.JoinDirectory(windowDir, pane.Directory)
+			}
+
+			var commands []string
+			if j == 0 && window.GitBranch != "" {
+				commands = append(commands, "git checkout "+window.GitBranch)
+			}
+			if pane.InitialCommand != "" {
+				commands = append(commands, pane.InitialCommand)
+			}
+			switch len(commands) {
+			case 0:
+			case 1:
+				p.ShellCommand = commands[0]
+			default:
+				p.ShellCommand = commands
+			}
+
+			if pane.PreCommand != "" || pane.PostCommand != "" {
+				r.notef("%s: pre_command and post_command can't be expressed", paneLabel)
+			}
+			if pane.RefreshInterval > 0 {
+				r.notef("%s: refresh_interval can't be expressed", paneLabel)
+			}
+			if pane.ForEach != nil {
+				r.notef("%s: for_each with a command wasn't expanded", paneLabel)
+			}
+			switch {
+			case p.StartDirectory == "" && len(p.Environment) == 0 && p.ShellCommand == nil:
+				// a blank pane
+				w.Panes = append(w.Panes, nil)
+			case p.StartDirectory == "" && len(p.Environment) == 0 && len(commands) == 1:
+				w.Panes = append(w.Panes, commands[0])
+			default:
+				w.Panes = append(w.Panes, p)
+			}
+		}
+		session.Windows = append(session.Windows, w)
+	}
+	return session, r.Notes
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
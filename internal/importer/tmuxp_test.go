@@ -0,0 +1,201 @@
+package importer
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+func TestTmuxpFixtures(t *testing.T) {
+	before := "source .venv/bin/activate; "
+	focus := 1
+	want := Result{
+		Name: "api",
+		Root: "~/src/api",
+		Config: config.Config{
+			SessionName: "api",
+			FocusWindow: &focus,
+			Defaults:    config.GlobalDefaults{Directory: "~/src/api", PreCommand: "./bootstrap.sh"},
+			Env:         map[string]string{"APP_ENV": "dev"},
+			Windows: []config.WindowConfig{
+				{Name: "editor", Layout: "main-vertical", Panes: []config.PaneConfig{
+					{InitialCommand: before + "vim"},
+					{InitialCommand: "source .venv/bin/activate"},
+					{Directory: "src", InitialCommand: before + "make watch; clear"},
+				}},
+				{Name: "server", Directory: "cmd/server", Env: map[string]string{"LOG_LEVEL": "debug"}, Panes: []config.PaneConfig{
+					{InitialCommand: before + "export PORT=8080; go run .", Env: map[string]string{"GOFLAGS": "-race"}},
+				}},
+				{Name: "logs", Panes: []config.PaneConfig{{InitialCommand: "source .venv/bin/activate"}}},
+			},
+		},
+		Notes: []string{
+			"tmux options can't be set per project",
+			"editor, pane 3: panes can't be focused",
+			"editor, pane 3: commands are always run, enter: false isn't supported",
+			`logs: custom layout "7b3c,204x51,0,0,1" isn't supported, left out`,
+			"logs: suppress_history isn't supported",
+		},
+	}
+
+	// The JSON fixture is the YAML one converted
+	for _, file := range []string{"testdata/tmuxp.yaml", "testdata/tmuxp.json"} {
+		t.Run(file, func(t *testing.T) {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Tmuxp(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Tmuxp() =\n%+v\nwant\n%+v", got, want)
+			}
+		})
+	}
+}
+
+func TestTmuxp(t *testing.T) {
+	tests := []struct {
+		name    string
+		session string
+		windows []config.WindowConfig
+		focus   int
+		notes   []string
+		wantErr bool
+	}{
+		{
+			name:    "window without panes",
+			session: "windows:\n  - window_name: shell\n",
+			windows: []config.WindowConfig{{Name: "shell", Panes: []config.PaneConfig{{}}}},
+		},
+		{
+			name:    "empty panes",
+			session: "windows:\n  - window_name: shell\n    panes:\n      - null\n      - pane\n",
+			windows: []config.WindowConfig{{Name: "shell", Panes: []config.PaneConfig{{}, {}}}},
+		},
+		{
+			name:    "unnamed window in notes",
+			session: "windows:\n  - options:\n      automatic-rename: on\n",
+			windows: []config.WindowConfig{{Panes: []config.PaneConfig{{}}}},
+			notes:   []string{"window 1: window options aren't supported"},
+		},
+		{
+			name:    "focus as a string",
+			session: "windows:\n  - window_name: a\n  - window_name: b\n    focus: 'true'\n",
+			windows: []config.WindowConfig{
+				{Name: "a", Panes: []config.PaneConfig{{}}},
+				{Name: "b", Panes: []config.PaneConfig{{}}},
+			},
+			focus: 2,
+		},
+		{
+			name:    "unknown session option",
+			session: "suppress_history: true\n",
+			notes:   []string{"suppress_history isn't supported"},
+		},
+		{
+			name:    "pane that is a list",
+			session: "windows:\n  - window_name: a\n    panes:\n      - [vim]\n",
+			wantErr: true,
+		},
+		{
+			name:    "invalid YAML",
+			session: "windows: [\n",
+			wantErr: true,
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			got, err := Tmuxp([]byte(test.session))
+			if test.wantErr {
+				if err == nil {
+					t.Errorf("Tmuxp() succeeded, want an error")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Config.Windows, test.windows) {
+				t.Errorf("windows =\n%+v\nwant\n%+v", got.Config.Windows, test.windows)
+			}
+			if focus := got.Config.Focus(); focus != test.focus {
+				t.Errorf("focus = %d, want %d", focus, test.focus)
+			}
+			if !reflect.DeepEqual(got.Notes, test.notes) {
+				t.Errorf("notes =\n%q\nwant\n%q", got.Notes, test.notes)
+			}
+		})
+	}
+}
+
+// TestExportTmuxpRoundTrip checks that importing an exported session gives
+// back what tmux-setup runs: the commands of panes, not those of windows
+func TestExportTmuxpRoundTrip(t *testing.T) {
+	focus := 2
+	cfg := config.Config{
+		SessionName: "api",
+		FocusWindow: &focus,
+		Defaults:    config.GlobalDefaults{Directory: "~/src/api", PreCommand: "./bootstrap.sh"},
+		Env:         map[string]string{"APP_ENV": "dev"},
+		Windows: []config.WindowConfig{
+			{Name: "editor", Layout: "main-vertical", Panes: []config.PaneConfig{
+				{InitialCommand: "vim"},
+				{Directory: "src", InitialCommand: "make watch", Env: map[string]string{"GOFLAGS": "-race"}},
+			}},
+			{Name: "server", Directory: "cmd/server", InitialCommand: "never run", Panes: []config.PaneConfig{
+				{InitialCommand: "go run ."},
+			}},
+			{Name: "scratch", InitialCommand: "htop"},
+		},
+	}
+	want := config.Config{
+		SessionName: "api",
+		FocusWindow: &focus,
+		Defaults:    config.GlobalDefaults{Directory: "~/src/api", PreCommand: "./bootstrap.sh"},
+		Env:         map[string]string{"APP_ENV": "dev"},
+		Windows: []config.WindowConfig{
+			{Name: "editor", Layout: "main-vertical", Panes: []config.PaneConfig{
+				{InitialCommand: "vim"},
+				{Directory: config.JoinDirectory("~/src/api", "src"), InitialCommand: "make watch", Env: map[string]string{"GOFLAGS": "-race"}},
+			}},
+			{Name: "server", Directory: "cmd/server", Panes: []config.PaneConfig{{InitialCommand: "go run ."}}},
+			{Name: "scratch", Panes: []config.PaneConfig{{}}},
+		},
+	}
+	wantNotes := []string{
+		"server: initial_command isn't run for windows, only for panes",
+		"scratch: initial_command isn't run for windows, only for panes",
+	}
+
+	session, notes := ExportTmuxp(cfg)
+	if !reflect.DeepEqual(notes, wantNotes) {
+		t.Errorf("ExportTmuxp() notes =\n%q\nwant\n%q", notes, wantNotes)
+	}
+
+	for name, marshal := range map[string]func(interface{}) ([]byte, error){"yaml": yaml.Marshal, "json": json.Marshal} {
+		t.Run(name, func(t *testing.T) {
+			data, err := marshal(session)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := Tmuxp(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got.Config, want) {
+				t.Errorf("Tmuxp(ExportTmuxp()) =\n%+v\nwant\n%+v\n%s", got.Config, want, data)
+			}
+			if len(got.Notes) > 0 {
+				t.Errorf("Tmuxp(ExportTmuxp()) notes = %q", got.Notes)
+			}
+		})
+	}
+}
@@ -2,10 +2,12 @@ package tmux
 
 import (
 	"fmt"
-	"log"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
@@ -14,20 +16,100 @@ import (
 	"github.com/bartosz-skejcik/tmux-setup/internal/hooks"
 )
 
+// Socket is the name of the tmux server socket (tmux -L), empty for the
+// default server
+var Socket string
+
+// tmuxCommand returns a tmux command talking to the configured server
+func tmuxCommand(args ...string) *exec.Cmd {
+	return exec.Command("tmux", commandArgs(args...)...)
+}
+
+func commandArgs(args ...string) []string {
+	if Socket == "" {
+		return args
+	}
+	return append([]string{"-L", Socket}, args...)
+}
+
 // CheckDependencies verifies all required dependencies are available
-func CheckDependencies(dependencies []string) {
+func CheckDependencies(dependencies []string) error {
 	for _, dep := range dependencies {
 		if _, err := exec.LookPath(dep); err != nil {
-			log.Fatalf("Dependency missing: %s", dep)
+			return fmt.Errorf("dependency missing: %s", dep)
+		}
+	}
+	return nil
+}
+
+// SessionInfo describes a running tmux session
+type SessionInfo struct {
+	Name     string
+	Windows  int
+	Attached bool
+	// Profile lists the profiles the session was started with
+	Profile string
+}
+
+// ProfileOption is the session option recording the profiles a session was
+// started with
+const ProfileOption = "@tmux_setup_profile"
+
+// HasSession reports whether a session with the given name is running
+func HasSession(sessionName string) bool {
+	return tmuxCommand("has-session", "-t", "="+sessionName).Run() == nil
+}
+
+// KillSession kills a running session
+func KillSession(sessionName string) error {
+	if output, err := tmuxCommand("kill-session", "-t", "="+sessionName).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to kill session %s: %s", sessionName, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// ListSessions returns the running tmux sessions
+func ListSessions() ([]SessionInfo, error) {
+	format := "#{session_name}\t#{session_windows}\t#{session_attached}\t#{" + ProfileOption + "}"
+	output, err := tmuxCommand("list-sessions", "-F", format).Output()
+	if err != nil {
+		// tmux exits with an error when no server is running
+		return nil, nil
+	}
+
+	var sessions []SessionInfo
+	// Only newlines are trimmed, as the last field may be empty
+	for _, line := range strings.Split(strings.TrimRight(string(output), "\n"), "\n") {
+		fields := strings.Split(line, "\t")
+		if len(fields) != 4 {
+			continue
 		}
+		windows, _ := strconv.Atoi(fields[1])
+		attached, _ := strconv.Atoi(fields[2])
+		sessions = append(sessions, SessionInfo{Name: fields[0], Windows: windows, Attached: attached > 0, Profile: fields[3]})
 	}
+	return sessions, nil
+}
+
+// SetSessionOption sets a user option (@name) on the session
+func SetSessionOption(sessionName, option, value string) error {
+	if output, err := tmuxCommand("set-option", "-t", "="+sessionName+":", option, value).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to set %s: %s", option, strings.TrimSpace(string(output)))
+	}
+	return nil
 }
 
 // CreateSession creates a new tmux session with the given configuration
 func CreateSession(sessionName string, cfg config.Config) error {
-	exec.Command("tmux", "new-session", "-d", "-s", sessionName, "-n", "placeholder").Run()
-	exec.Command("tmux", "set-option", "-g", "base-index", "1").Run()
-	exec.Command("tmux", "set-window-option", "-g", "pane-base-index", "1").Run()
+	newSession := append([]string{"new-session", "-d", "-s", sessionName, "-n", "placeholder"}, envArgs(cfg.Env)...)
+	tmuxCommand(newSession...).Run()
+	tmuxCommand("set-option", "-g", "base-index", "1").Run()
+	tmuxCommand("set-window-option", "-g", "pane-base-index", "1").Run()
+
+	// Session environment is inherited by every window and pane created later
+	for _, key := range sortedKeys(cfg.Env) {
+		tmuxCommand("set-environment", "-t", sessionName, key, cfg.Env[key]).Run()
+	}
 
 	for i, window := range cfg.Windows {
 		if err := createWindow(sessionName, i, window, cfg.Defaults); err != nil {
@@ -35,23 +117,173 @@ func CreateSession(sessionName string, cfg config.Config) error {
 		}
 	}
 
+	if err := registerHooks(sessionName, cfg); err != nil {
+		return fmt.Errorf("failed to register tmux hooks: %v", err)
+	}
+
 	return nil
 }
 
-// AttachSession attaches to an existing tmux session
+// registerHooks binds the configured tmux_hooks to the session with set-hook.
+// The defaults' post_command runs when the session is closed unless
+// session-closed is bound explicitly.
+func registerHooks(sessionName string, cfg config.Config) error {
+	// A session of this name that was started before may have left its
+	// session-closed hook behind
+	if err := setClosedHook(sessionName, ""); err != nil {
+		return fmt.Errorf("session-closed: %v", err)
+	}
+
+	bindings := make(map[string]string, len(cfg.TmuxHooks)+1)
+	for event, command := range cfg.TmuxHooks {
+		if !slices.Contains(config.TmuxHookEvents, event) {
+			return fmt.Errorf("unsupported tmux event %q (supported: %s)", event, strings.Join(config.TmuxHookEvents, ", "))
+		}
+		bindings[event] = command
+	}
+	if _, ok := bindings["session-closed"]; !ok && cfg.Defaults.PostCommand != "" {
+		bindings["session-closed"] = "@post_command"
+	}
+
+	for _, event := range config.TmuxHookEvents {
+		value, ok := bindings[event]
+		if !ok {
+			continue
+		}
+
+		command, err := hookCommand(cfg, value)
+		if err != nil {
+			return fmt.Errorf("%s: %v", event, err)
+		}
+		if command == "" {
+			continue
+		}
+
+		if event == "session-closed" {
+			err = setClosedHook(sessionName, command)
+		} else {
+			runShell := fmt.Sprintf("run-shell -b %s", quoteCommand(command))
+			err = tmuxCommand("set-hook", "-t", sessionName, event, runShell).Run()
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %v", event, err)
+		}
+	}
+
+	return nil
+}
+
+var hookIndex = regexp.MustCompile(`^session-closed\[(\d+)\] `)
+
+// setClosedHook runs command when the session closes, replacing what an
+// earlier session of the name bound. tmux runs only global session-closed
+// hooks, as the session is gone by then, so the hook is global with a guard
+// on the session's name, and removes itself once it has run.
+func setClosedHook(sessionName, command string) error {
+	output, err := tmuxCommand("show-hooks", "-g", "session-closed").Output()
+	if err != nil {
+		return err
+	}
+
+	// tmux formats escape commas and braces with #
+	name := strings.NewReplacer("#", "##", ",", "#,", "}", "#}").Replace(sessionName)
+	guard := quoteCommand("#{==:#{hook_session_name}," + name + "}")
+	next := 0
+	for _, line := range strings.Split(string(output), "\n") {
+		m := hookIndex.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+		index, _ := strconv.Atoi(m[1])
+		next = max(next, index+1)
+		if strings.Contains(line, "if-shell -F "+guard+" ") {
+			if err := tmuxCommand("set-hook", "-gu", fmt.Sprintf("session-closed[%d]", index)).Run(); err != nil {
+				return err
+			}
+		}
+	}
+	if command == "" {
+		return nil
+	}
+
+	hook := fmt.Sprintf("session-closed[%d]", next)
+	run := fmt.Sprintf("run-shell -b %s ; set-hook -gu %s", quoteCommand(command), hook)
+	return tmuxCommand("set-hook", "-g", hook, fmt.Sprintf("if-shell -F %s %s", guard, quoteCommand(run))).Run()
+}
+
+// hookCommand resolves a tmux_hooks value to the shell command to run
+func hookCommand(cfg config.Config, value string) (string, error) {
+	callback, ok := strings.CutPrefix(value, "@")
+	if !ok {
+		return value, nil
+	}
+
+	switch callback {
+	case "pre_command":
+		return cfg.Defaults.PreCommand, nil
+	case "post_command":
+		return cfg.Defaults.PostCommand, nil
+	}
+	return "", fmt.Errorf("unknown callback %q (supported: @%s)", value, strings.Join(config.TmuxHookCallbacks, ", @"))
+}
+
+// quoteCommand wraps a shell command in double quotes for the tmux command parser
+func quoteCommand(command string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, `"`, `\"`, `$`, `\$`)
+	return `"` + replacer.Replace(command) + `"`
+}
+
+// AttachSession focuses the given window and attaches to an existing tmux session
 func AttachSession(sessionName string, focusWindow int) error {
 	if focusWindow == 0 {
 		focusWindow = 1
 	}
 
+	tmuxCommand("select-window", "-t", fmt.Sprintf("%s:%d", sessionName, focusWindow)).Run()
+
+	return Attach(sessionName)
+}
+
+// SelectWindow makes the named window the current window of the session
+func SelectWindow(sessionName, windowName string) error {
+	if output, err := tmuxCommand("select-window", "-t", sessionName+":"+windowName).CombinedOutput(); err != nil {
+		return fmt.Errorf("failed to select window %s: %s", windowName, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+// Attach replaces the current process with a tmux client attached to the
+// session. Inside tmux the current client is switched to it instead.
+func Attach(sessionName string) error {
+	if insideServer() {
+		if output, err := tmuxCommand("switch-client", "-t", sessionName).CombinedOutput(); err != nil {
+			return fmt.Errorf("failed to switch to session %s: %s", sessionName, strings.TrimSpace(string(output)))
+		}
+		return nil
+	}
+
 	tmuxPath, err := exec.LookPath("tmux")
 	if err != nil {
 		return err
 	}
 
-	exec.Command("tmux", "select-window", "-t", fmt.Sprintf("%s:%d", sessionName, focusWindow)).Run()
+	args := append([]string{"tmux"}, commandArgs("attach-session", "-t", sessionName)...)
+	return syscall.Exec(tmuxPath, args, os.Environ())
+}
 
-	return syscall.Exec(tmuxPath, []string{"tmux", "attach-session", "-t", sessionName}, os.Environ())
+// insideServer reports whether tmux-setup runs inside a client of the
+// configured tmux server
+func insideServer() bool {
+	// $TMUX is "<socket path>,<pid>,<session>"
+	socketPath, _, _ := strings.Cut(os.Getenv("TMUX"), ",")
+	if socketPath == "" {
+		return false
+	}
+	socket := Socket
+	if socket == "" {
+		socket = "default"
+	}
+	return filepath.Base(socketPath) == socket
 }
 
 func createWindow(sessionName string, index int, window config.WindowConfig, defaults config.GlobalDefaults) error {
@@ -64,14 +296,26 @@ func createWindow(sessionName string, index int, window config.WindowConfig, def
 		return err
 	}
 
+	// The window's first pane gets the window environment and its own
+	firstPaneEnv := window.Env
+	if len(window.Panes) > 0 {
+		firstPaneEnv = config.MergeEnv(window.Env, window.Panes[0].Env)
+	}
+
 	if index == 0 {
-		exec.Command("tmux", "rename-window", "-t", fmt.Sprintf("%s:1", sessionName), windowName).Run()
+		tmuxCommand("rename-window", "-t", fmt.Sprintf("%s:1", sessionName), windowName).Run()
+		// The first window already exists, so its shell is restarted with the environment
+		if len(firstPaneEnv) > 0 {
+			respawn := append([]string{"respawn-pane", "-k", "-t", fmt.Sprintf("%s:1.1", sessionName)}, envArgs(firstPaneEnv)...)
+			tmuxCommand(respawn...).Run()
+		}
 	} else {
-		exec.Command("tmux", "new-window", "-t", fmt.Sprintf("%s:%d", sessionName, index+1), "-n", windowName).Run()
+		newWindow := append([]string{"new-window", "-t", fmt.Sprintf("%s:%d", sessionName, index+1), "-n", windowName}, envArgs(firstPaneEnv)...)
+		tmuxCommand(newWindow...).Run()
 	}
 
 	// Set working directory
-	dir := resolveDirectory(defaults.Directory, window.Directory)
+	dir := config.JoinDirectory(defaults.Directory, window.Directory)
 	if dir != "" {
 		sendKeys(sessionName, index+1, 1, fmt.Sprintf("cd %s", dir))
 	}
@@ -100,10 +344,12 @@ func createPanes(sessionName string, windowIndex int, window config.WindowConfig
 			if layout, ok := window.Layout.(string); ok && strings.Contains(layout, "vertical") {
 				splitType = "-v"
 			}
-			exec.Command("tmux", "split-window", splitType, "-t", fmt.Sprintf("%s:%d", sessionName, windowIndex)).Run()
+			split := append([]string{"split-window", splitType, "-t", fmt.Sprintf("%s:%d", sessionName, windowIndex)},
+				envArgs(config.MergeEnv(window.Env, pane.Env))...)
+			tmuxCommand(split...).Run()
 		}
 
-		paneDir := resolveDirectory(defaultDir, pane.Directory)
+		paneDir := config.JoinDirectory(defaultDir, pane.Directory)
 		if paneDir != "" {
 			sendKeys(sessionName, windowIndex, i+1, fmt.Sprintf("cd %s", paneDir))
 		}
@@ -135,16 +381,16 @@ func refreshPane(sessionName string, windowIndex, paneIndex int, pane config.Pan
 func applyLayout(sessionName string, windowIndex int, layout interface{}) {
 	switch l := layout.(type) {
 	case string:
-		exec.Command("tmux", "select-layout", "-t", fmt.Sprintf("%s:%d", sessionName, windowIndex), l).Run()
+		tmuxCommand("select-layout", "-t", fmt.Sprintf("%s:%d", sessionName, windowIndex), l).Run()
 	case config.LayoutConfig:
 		// Apply custom layout using resize-pane commands
 		for i, pane := range l.Panes {
 			if pane.Width != "" {
-				exec.Command("tmux", "resize-pane", "-t", fmt.Sprintf("%s:%d.%d", sessionName, windowIndex, i+1),
+				tmuxCommand("resize-pane", "-t", fmt.Sprintf("%s:%d.%d", sessionName, windowIndex, i+1),
 					"-x", strings.TrimSuffix(pane.Width, "%")).Run()
 			}
 			if pane.Height != "" {
-				exec.Command("tmux", "resize-pane", "-t", fmt.Sprintf("%s:%d.%d", sessionName, windowIndex, i+1),
+				tmuxCommand("resize-pane", "-t", fmt.Sprintf("%s:%d.%d", sessionName, windowIndex, i+1),
 					"-y", strings.TrimSuffix(pane.Height, "%")).Run()
 			}
 		}
@@ -152,16 +398,24 @@ func applyLayout(sessionName string, windowIndex int, layout interface{}) {
 }
 
 func sendKeys(sessionName string, windowIndex, paneIndex int, command string) {
-	exec.Command("tmux", "send-keys", "-t", fmt.Sprintf("%s:%d.%d", sessionName, windowIndex, paneIndex),
+	tmuxCommand("send-keys", "-t", fmt.Sprintf("%s:%d.%d", sessionName, windowIndex, paneIndex),
 		command, "C-m").Run()
 }
 
-func resolveDirectory(parent, child string) string {
-	if child != "" {
-		if filepath.IsAbs(child) {
-			return child
-		}
-		return filepath.Join(parent, child)
+// envArgs returns -e flags setting the variables on a new window or pane
+func envArgs(env map[string]string) []string {
+	var args []string
+	for _, key := range sortedKeys(env) {
+		args = append(args, "-e", key+"="+env[key])
+	}
+	return args
+}
+
+func sortedKeys(env map[string]string) []string {
+	keys := make([]string, 0, len(env))
+	for key := range env {
+		keys = append(keys, key)
 	}
-	return parent
+	slices.Sort(keys)
+	return keys
 }
@@ -0,0 +1,273 @@
+package trust
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+	"gopkg.in/yaml.v3"
+)
+
+// Status describes whether a config file may run its commands
+type Status int
+
+const (
+	// Unknown configs have never been allowed or denied
+	Unknown Status = iota
+	// Allowed configs match the hash they were approved with
+	Allowed
+	// Denied configs match the hash they were denied with
+	Denied
+	// Changed configs were allowed or denied, but have been modified since
+	Changed
+)
+
+func (s Status) String() string {
+	switch s {
+	case Allowed:
+		return "allowed"
+	case Denied:
+		return "denied"
+	case Changed:
+		return "changed"
+	}
+	return "unknown"
+}
+
+// Entry is a config file recorded in the trust store
+type Entry struct {
+	Path   string
+	Hash   string
+	Status Status
+}
+
+// store is the on-disk format of the trust store
+type store struct {
+	Allowed map[string]string `yaml:"allowed"`
+	Denied  map[string]string `yaml:"denied"`
+}
+
+// Check reports the trust status of the config file at path. files are the
+// other files it reads, such as includes and env files, which must be
+// unchanged too.
+func Check(path string, files []string) (Status, error) {
+	path, hash, err := hashFiles(path, files)
+	if err != nil {
+		return Unknown, err
+	}
+
+	s, err := loadStore()
+	if err != nil {
+		return Unknown, err
+	}
+
+	if allowed, ok := s.Allowed[path]; ok {
+		if allowed == hash {
+			return Allowed, nil
+		}
+		return Changed, nil
+	}
+	if denied, ok := s.Denied[path]; ok {
+		if denied == hash {
+			return Denied, nil
+		}
+		return Changed, nil
+	}
+	return Unknown, nil
+}
+
+// Allow approves the current contents of the config file at path and of
+// the files it reads
+func Allow(path string, files []string) error {
+	return record(path, files, true)
+}
+
+// Deny blocks the current contents of the config file at path and of the
+// files it reads
+func Deny(path string, files []string) error {
+	return record(path, files, false)
+}
+
+// List returns all config files recorded in the trust store, sorted by path
+func List() ([]Entry, error) {
+	s, err := loadStore()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []Entry
+	for path, hash := range s.Allowed {
+		entries = append(entries, Entry{Path: path, Hash: hash, Status: Allowed})
+	}
+	for path, hash := range s.Denied {
+		entries = append(entries, Entry{Path: path, Hash: hash, Status: Denied})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Path < entries[j].Path
+	})
+	return entries, nil
+}
+
+// Commands returns every command the configuration would execute
+func Commands(cfg config.Config) []string {
+	var commands []string
+	add := func(label, command string) {
+		if command != "" {
+			commands = append(commands, fmt.Sprintf("%s: %s", label, command))
+		}
+	}
+
+	for _, dep := range cfg.Dependencies {
+		add("dependency", dep)
+	}
+	add("defaults.pre_command", cfg.Defaults.PreCommand)
+	add("defaults.initial_command", cfg.Defaults.InitialCommand)
+	add("defaults.post_command", cfg.Defaults.PostCommand)
+
+	events := make([]string, 0, len(cfg.TmuxHooks))
+	for event := range cfg.TmuxHooks {
+		events = append(events, event)
+	}
+	sort.Strings(events)
+	for _, event := range events {
+		add("tmux_hooks."+event, cfg.TmuxHooks[event])
+	}
+
+	for i, window := range cfg.Windows {
+		prefix := fmt.Sprintf("windows[%d]", i)
+		add(prefix+".pre_command", window.PreCommand)
+		add(prefix+".initial_command", window.InitialCommand)
+		if window.GitBranch != "" {
+			add(prefix+".git_branch", "git checkout "+window.GitBranch)
+		}
+		add(prefix+".post_command", window.PostCommand)
+		if window.When != nil {
+			add(prefix+".when.command", window.When.Command)
+		}
+		if window.ForEach != nil {
+			add(prefix+".for_each.command", window.ForEach.Command)
+		}
+
+		for j, pane := range window.Panes {
+			prefix := fmt.Sprintf("windows[%d].panes[%d]", i, j)
+			if pane.When != nil {
+				add(prefix+".when.command", pane.When.Command)
+			}
+			if pane.ForEach != nil {
+				add(prefix+".for_each.command", pane.ForEach.Command)
+			}
+			add(prefix+".pre_command", pane.PreCommand)
+			add(prefix+".initial_command", pane.InitialCommand)
+			add(prefix+".post_command", pane.PostCommand)
+		}
+	}
+
+	return commands
+}
+
+func record(path string, files []string, allow bool) error {
+	path, hash, err := hashFiles(path, files)
+	if err != nil {
+		return err
+	}
+
+	s, err := loadStore()
+	if err != nil {
+		return err
+	}
+
+	delete(s.Allowed, path)
+	delete(s.Denied, path)
+	if allow {
+		s.Allowed[path] = hash
+	} else {
+		s.Denied[path] = hash
+	}
+
+	return saveStore(s)
+}
+
+// hashFiles returns the absolute path of a config file and the hash of the
+// paths and contents of it and the files it reads
+func hashFiles(path string, files []string) (string, string, error) {
+	absPath, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	// A config file without other files hashes as it always has, so its
+	// approval stays valid
+	sum := sha256.New()
+	hashed := map[string]bool{}
+	for i, file := range append([]string{absPath}, files...) {
+		if file, err = filepath.Abs(file); err != nil {
+			return "", "", err
+		}
+		if hashed[file] {
+			continue
+		}
+		hashed[file] = true
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return "", "", err
+		}
+		if i > 0 {
+			sum.Write([]byte{0})
+		}
+		sum.Write([]byte(file))
+		sum.Write([]byte{0})
+		sum.Write(data)
+	}
+	return absPath, hex.EncodeToString(sum.Sum(nil)), nil
+}
+
+func loadStore() (store, error) {
+	s := store{}
+
+	path, err := getStorePath()
+	if err != nil {
+		return s, err
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return s, err
+	}
+	if err := yaml.Unmarshal(data, &s); err != nil {
+		return s, fmt.Errorf("invalid trust store %s: %v", path, err)
+	}
+
+	if s.Allowed == nil {
+		s.Allowed = map[string]string{}
+	}
+	if s.Denied == nil {
+		s.Denied = map[string]string{}
+	}
+	return s, nil
+}
+
+func saveStore(s store) error {
+	path, err := getStorePath()
+	if err != nil {
+		return err
+	}
+
+	data, err := yaml.Marshal(s)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0600)
+}
+
+func getStorePath() (string, error) {
+	configDir, err := config.GetConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(configDir, "trust.yml"), nil
+}
